@@ -0,0 +1,173 @@
+package badgerhelper
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// backup files in one directory form a chain: a full backup then incrementals on top of it.
+// manifest.json in that directory records the chain in order.
+
+const (
+	backupManifest   = "manifest.json"
+	maxPendingWrites = 256
+)
+
+type BackupEntry struct {
+	File    string    `json:"file"`
+	Full    bool      `json:"full"`
+	Since   uint64    `json:"since"`   // entries with version > Since are in this backup
+	Version uint64    `json:"version"` // last dumped version, next incremental is since Version
+	Time    time.Time `json:"time"`
+}
+
+type BackupManifest struct {
+	Backups []BackupEntry `json:"backups"`
+}
+
+// manifest of backup directory, empty one if directory has no backup yet
+func ReadBackupManifest(dir string) (*BackupManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, backupManifest))
+	if errors.Is(err, os.ErrNotExist) {
+		return &BackupManifest{Backups: []BackupEntry{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	manifest := &BackupManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func (m *BackupManifest) write(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, backupManifest+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, backupManifest))
+}
+
+// gzip compressed db.Backup since version into file, written to a temp file first
+func backupTo(db *badger.DB, file string, since uint64) (uint64, error) {
+	tmp := file + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	ver, err := db.Backup(zw, since)
+	if err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	if err := f.Sync(); err != nil {
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	return ver, os.Rename(tmp, file)
+}
+
+func backup(db *badger.DB, dir string, full bool) (BackupEntry, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return BackupEntry{}, err
+	}
+	manifest, err := ReadBackupManifest(dir)
+	if err != nil {
+		return BackupEntry{}, err
+	}
+
+	entry := BackupEntry{Full: full || len(manifest.Backups) == 0, Time: time.Now()}
+	if !entry.Full {
+		last := manifest.Backups[len(manifest.Backups)-1]
+		// badger Stream skips versions <= SinceTs, so chain on last version itself
+		entry.Since = last.Version
+	}
+	kind := "incr"
+	if entry.Full {
+		kind = "full"
+	}
+	entry.File = fmt.Sprintf("backup-%04d-%s.bak.gz", len(manifest.Backups)+1, kind)
+
+	ver, err := backupTo(db, filepath.Join(dir, entry.File), entry.Since)
+	if err != nil {
+		return BackupEntry{}, err
+	}
+	// nothing newer may be dumped, then chain stays at previous version
+	entry.Version = max(ver, entry.Since)
+
+	manifest.Backups = append(manifest.Backups, entry)
+	return entry, manifest.write(dir)
+}
+
+// full backup of db into dir, which starts a new backup chain
+func BackupFull(db *badger.DB, dir string) (BackupEntry, error) {
+	return backup(db, dir, true)
+}
+
+// backup of entries changed since last backup in dir, full backup if dir has none yet
+func BackupIncremental(db *badger.DB, dir string) (BackupEntry, error) {
+	return backup(db, dir, false)
+}
+
+// -------------------------------------------------------------------- //
+
+func loadFrom(db *badger.DB, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	return db.Load(zr, maxPendingWrites)
+}
+
+// replay the latest backup chain in dir into db: the last full backup, then all incrementals after it.
+// db should not be serving other transactions while restoring.
+func Restore(db *badger.DB, dir string) (restored []BackupEntry, err error) {
+	manifest, err := ReadBackupManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	start := -1
+	for i, entry := range manifest.Backups {
+		if entry.Full {
+			start = i
+		}
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("no full backup in '%s'", dir)
+	}
+	for _, entry := range manifest.Backups[start:] {
+		if err := loadFrom(db, filepath.Join(dir, entry.File)); err != nil {
+			return restored, fmt.Errorf("restore '%s': %w", entry.File, err)
+		}
+		restored = append(restored, entry)
+	}
+	return restored, nil
+}
@@ -0,0 +1,142 @@
+package badgerhelper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// deletes committed in one transaction by DeleteObjectsChunked when chunk is not given
+const DefaultDeleteChunk = 1000
+
+// delete objects under prefix (all objects if prefix is nil or empty) in transactions of up to chunk deletes,
+// so big prefixes don't abort with badger.ErrTxnTooBig like DeleteObjects.
+// filter selects objects to delete, nil deletes all. progress (may be nil) gets the total deleted count after each committed chunk.
+// n is the exact count of committed deletes, also when ctx is done or an error stops deleting halfway.
+// see DropObjects to drop a whole prefix faster without count.
+func DeleteObjectsChunked[V any, T PtrDbAccessible[V]](ctx context.Context, prefix []byte, filter func(T) bool, chunk int, progress func(deleted int)) (n int, err error) {
+	db := T(new(V)).BadgerDB()
+	if chunk <= 0 {
+		chunk = DefaultDeleteChunk
+	}
+	if progress == nil {
+		progress = func(int) {}
+	}
+
+	var (
+		from = append([]byte{}, prefix...)
+		next []byte
+	)
+	err = updateInChunks(db, chunk, func(txn *badger.Txn, chunk int) (deleted int, done bool, err error) {
+		if err := ctx.Err(); err != nil {
+			return 0, false, err
+		}
+		deleted, next, err = deleteChunk[V, T](ctx, txn, prefix, from, filter, chunk)
+		return deleted, next == nil, err
+	}, func(deleted int) {
+		n += deleted
+		progress(n)
+		from = next
+	})
+	return n, err
+}
+
+// delete up to chunk objects under prefix from key 'from' on, next is the key to resume at, nil when prefix is done
+func deleteChunk[V any, T PtrDbAccessible[V]](ctx context.Context, txn *badger.Txn, prefix, from []byte, filter func(T) bool, chunk int) (n int, next []byte, err error) {
+	it := txn.NewIterator(scanOptions(prefix, false))
+	defer it.Close()
+
+	for it.Seek(from); it.ValidForPrefix(prefix); it.Next() {
+		item := it.Item()
+		if hidden(prefix, item.Key()) {
+			continue
+		}
+		if n == chunk || ctx.Err() != nil {
+			return n, item.KeyCopy(nil), nil
+		}
+		if filter != nil {
+			one := T(new(V))
+			if err := item.Value(func(val []byte) error {
+				_, err := one.Unmarshal(item.Key(), val)
+				return err
+			}); err != nil {
+				return n, nil, err
+			}
+			if !filter(one) {
+				continue
+			}
+		}
+		if err := deleteItem[V, T](txn, item); err != nil {
+			return n, nil, err
+		}
+		n++
+	}
+	return n, nil, nil
+}
+
+// drop all objects under prefix by badger DropPrefix, much faster than deleting them one by one,
+// but no count is reported and it cannot be cancelled. writes to db are blocked while dropping.
+// prefix CANNOT be empty or cover reserved keys, and T CANNOT be Indexed as its index entries would be left behind.
+func DropObjects[V any, T PtrDbAccessible[V]](prefix []byte) error {
+	if len(prefix) == 0 {
+		return errors.New("prefix CANNOT be empty")
+	}
+	if bytes.HasPrefix(metaPrefix, prefix) {
+		return errors.New("prefix CANNOT cover reserved keys")
+	}
+	if isIndexed[V, T]() {
+		return errors.New("objects of Indexed type CANNOT be dropped, use DeleteObjectsChunked")
+	}
+	return T(new(V)).BadgerDB().DropPrefix(prefix)
+}
+
+// -------------------------------------------------------------------- //
+
+// run step in badger Update transactions until it reports done. step handles up to chunk items in txn, returns their count.
+// a chunk too big for one transaction (badger.ErrTxnTooBig) is halved and retried,
+// committed gets the item count of each committed transaction.
+func updateInChunks(db *badger.DB, chunk int, step func(txn *badger.Txn, chunk int) (n int, done bool, err error), committed func(n int)) error {
+	for {
+		var (
+			n    int
+			done bool
+		)
+		err := db.Update(func(txn *badger.Txn) (err error) {
+			n, done, err = step(txn, chunk)
+			return err
+		})
+		if errors.Is(err, badger.ErrTxnTooBig) && chunk > 1 {
+			chunk /= 2
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		committed(n)
+		if done {
+			return nil
+		}
+	}
+}
+
+// write items by set in transactions as big as possible (see updateInChunks),
+// committed is the count of items stored before an error.
+func updateEach[E any](db *badger.DB, items []E, set func(txn *badger.Txn, item E) error) (committed int, err error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+	err = updateInChunks(db, len(items), func(txn *badger.Txn, chunk int) (int, bool, error) {
+		chunk = min(chunk, len(items)-committed)
+		for _, item := range items[committed : committed+chunk] {
+			if err := set(txn, item); err != nil {
+				return 0, false, err
+			}
+		}
+		return chunk, committed+chunk == len(items), nil
+	}, func(n int) {
+		committed += n
+	})
+	return committed, err
+}
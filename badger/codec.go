@@ -0,0 +1,84 @@
+package badgerhelper
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+
+	lk "github.com/digisan/logkit"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// value encoding of stored objects
+type Codec interface {
+	Encode(v any) ([]byte, error)
+	Decode(data []byte, v any) error
+}
+
+var (
+	JSON    Codec = jsonCodec{}
+	Gob     Codec = gobCodec{}
+	MsgPack Codec = msgpackCodec{}
+	CBOR    Codec = cborCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(v any) ([]byte, error)    { return json.Marshal(v) }
+func (jsonCodec) Decode(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type gobCodec struct{}
+
+func (gobCodec) Encode(v any) ([]byte, error) {
+	buf := bytes.Buffer{}
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Decode(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Encode(v any) ([]byte, error)    { return msgpack.Marshal(v) }
+func (msgpackCodec) Decode(data []byte, v any) error { return msgpack.Unmarshal(data, v) }
+
+type cborCodec struct{}
+
+func (cborCodec) Encode(v any) ([]byte, error)    { return cbor.Marshal(v) }
+func (cborCodec) Decode(data []byte, v any) error { return cbor.Unmarshal(data, v) }
+
+// -------------------------------------------------------------------- //
+
+// Adapter derives DbAccessible Marshal & Unmarshal of struct S from a codec and a key function,
+// so S only needs to declare its BadgerDB and Key, then delegate Marshal & Unmarshal to Adapter.
+// only fields visible to codec (e.g. exported) are stored.
+type Adapter[S any] struct {
+	Codec  Codec
+	Key    func(s *S) []byte
+	SetKey func(s *S, key []byte) // optional, restore key fields from db key on Unmarshal
+}
+
+func (a Adapter[S]) Marshal(s *S) (forKey, forValue []byte) {
+	forKey = a.Key(s)
+	lk.FailOnErrWhen(len(forKey) == 0, "%v", errors.New("invalid(empty) key for BadgerDB"))
+	forValue, err := a.Codec.Encode(s)
+	lk.FailOnErr("%v", err)
+	return
+}
+
+func (a Adapter[S]) Unmarshal(s *S, dbKey, dbVal []byte) (any, error) {
+	*s = *new(S)
+	if err := a.Codec.Decode(dbVal, s); err != nil {
+		return nil, err
+	}
+	if a.SetKey != nil {
+		a.SetKey(s, dbKey)
+	}
+	return s, nil
+}
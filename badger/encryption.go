@@ -0,0 +1,116 @@
+package badgerhelper
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// badger encrypts data keys by an AES master key, which is 16, 24 or 32 bytes
+func checkEncryptionKey(key []byte) error {
+	switch len(key) {
+	case 16, 24, 32:
+		return nil
+	default:
+		return fmt.Errorf("encryption key MUST be 16, 24 or 32 bytes, got %d", len(key))
+	}
+}
+
+// random AES key of size 16, 24 or 32
+func NewEncryptionKey(size int) ([]byte, error) {
+	key := make([]byte, size)
+	if err := checkEncryptionKey(key); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// key from file holding raw key bytes, same as badger 'rotate' command reads
+func LoadEncryptionKey(file string) ([]byte, error) {
+	key, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkEncryptionKey(key); err != nil {
+		return nil, fmt.Errorf("'%s': %w", file, err)
+	}
+	return key, nil
+}
+
+// key from environment variable holding hex of key bytes
+func LoadEncryptionKeyEnv(name string) ([]byte, error) {
+	val, ok := os.LookupEnv(name)
+	if !ok || len(strings.TrimSpace(val)) == 0 {
+		return nil, fmt.Errorf("env '%s' CANNOT be empty", name)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(val))
+	if err != nil {
+		return nil, fmt.Errorf("env '%s' MUST be hex: %w", name, err)
+	}
+	if err := checkEncryptionKey(key); err != nil {
+		return nil, fmt.Errorf("env '%s': %w", name, err)
+	}
+	return key, nil
+}
+
+// re-encrypt data keys of closed db in dir by newKey. data itself is not rewritten, since it is encrypted by data keys.
+// oldKey empty means dir is not encrypted yet, newKey empty turns encryption off for tables written later.
+func RotateEncryptionKey(dir string, oldKey, newKey []byte) error {
+	if len(dir) == 0 {
+		return errors.New("dir CANNOT be empty")
+	}
+	for _, key := range [][]byte{oldKey, newKey} {
+		if len(key) > 0 {
+			if err := checkEncryptionKey(key); err != nil {
+				return err
+			}
+		}
+	}
+	if name, ok := openedAt(dir); ok {
+		return fmt.Errorf("db '%s' in '%s' MUST be closed before rotating key", name, dir)
+	}
+
+	opt := badger.KeyRegistryOptions{
+		Dir:           dir,
+		ReadOnly:      true,
+		EncryptionKey: oldKey,
+	}
+	kr, err := badger.OpenKeyRegistry(opt)
+	if err != nil {
+		return err
+	}
+	defer kr.Close()
+
+	opt.EncryptionKey = newKey
+	return badger.WriteKeyRegistry(kr, opt)
+}
+
+// name of registered db opened (or being opened) in dir
+func openedAt(dir string) (string, bool) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+
+	mtxReg.Lock()
+	defer mtxReg.Unlock()
+
+	for name, r := range registry {
+		if r.opts.InMemory || r.opts.Dir == "" {
+			continue
+		}
+		if d, err := filepath.Abs(r.opts.Dir); err == nil && d == abs {
+			return name, true
+		}
+	}
+	return "", false
+}
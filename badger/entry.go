@@ -0,0 +1,92 @@
+package badgerhelper
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// per object write option, applied on badger.Entry before it is set
+type EntryOption func(e *badger.Entry)
+
+// object expires after ttl
+func WithTTL(ttl time.Duration) EntryOption {
+	return func(e *badger.Entry) {
+		e.WithTTL(ttl)
+	}
+}
+
+// attach one user-meta byte to object, read back by badger.Item.UserMeta
+func WithUserMeta(meta byte) EntryOption {
+	return func(e *badger.Entry) {
+		e.WithMeta(meta)
+	}
+}
+
+// earlier versions of object can be discarded by compaction
+func WithDiscard() EntryOption {
+	return func(e *badger.Entry) {
+		e.WithDiscard()
+	}
+}
+
+// keep expiry time of an existing entry, 0 never expires
+func withExpiresAt(ts uint64) EntryOption {
+	return func(e *badger.Entry) {
+		e.ExpiresAt = ts
+	}
+}
+
+func newEntry(key, value []byte, opts ...EntryOption) *badger.Entry {
+	e := badger.NewEntry(key, value)
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// update or insert one object with entry options, e.g. TTL
+func UpsertOneObjectWith[V any, T PtrDbAccessible[V]](object T, opts ...EntryOption) error {
+	return object.BadgerDB().Update(func(txn *badger.Txn) error {
+		return upsertOneObject[V, T](txn, object, opts...)
+	})
+}
+
+// update or insert many objects, all with the same entry options
+func UpsertObjectsWith[V any, T PtrDbAccessible[V]](objects []T, opts ...EntryOption) error {
+	if isIndexed[V, T]() {
+		return upsertIndexedObjects[V, T](objects, opts...)
+	}
+
+	wb := T(new(V)).BadgerDB().NewWriteBatch()
+	defer wb.Cancel()
+
+	for _, object := range objects {
+		k, v := object.Marshal(nil)
+		if err := wb.SetEntry(newEntry(k, v, opts...)); err != nil {
+			return err
+		}
+	}
+	return wb.Flush()
+}
+
+// remaining time to live of object at key. 0 ttl for a found object means it never expires.
+func GetObjectTTL[V any, T PtrDbAccessible[V]](key []byte) (ttl time.Duration, found bool, err error) {
+	err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		found = true
+		if exp := item.ExpiresAt(); exp > 0 {
+			// keep ttl positive for an object about to expire, 0 is reserved for never-expiring
+			ttl = max(time.Until(time.Unix(int64(exp), 0)), time.Nanosecond)
+		}
+		return nil
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return 0, false, nil
+	}
+	return ttl, found, err
+}
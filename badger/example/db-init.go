@@ -2,57 +2,36 @@ package example
 
 import (
 	"path/filepath"
-	"sync"
 
 	"github.com/dgraph-io/badger/v4"
+	bh "github.com/digisan/db-helper/badger"
 	lk "github.com/digisan/logkit"
 )
 
-var (
-	onceEDB sync.Once // do once
-	dbGrp   *DBGrp    // global, for keeping single instance
+// names of example dbs in badgerhelper registry
+const (
+	db1Name = "db1"
+	db2Name = "db2"
 )
 
-type DBGrp struct {
-	sync.Mutex
-	db1 *badger.DB
-	db2 *badger.DB
-}
-
-func open(dir string) *badger.DB {
-	opt := badger.DefaultOptions("").WithInMemory(true)
-	if dir != "" {
-		opt = badger.DefaultOptions(dir)
-		opt.Logger = nil
+func dbOptions(dir, name string) bh.DBOptions {
+	if dir == "" {
+		return bh.DBOptions{InMemory: true, Logger: badger.DefaultOptions("").Logger}
 	}
-	db, err := badger.Open(opt)
-	lk.FailOnErr("%v", err)
-	return db
+	return bh.DBOptions{Dir: filepath.Join(dir, name)}
 }
 
-// init global 'dbGrp'
-func InitDB(dir string) *DBGrp {
-	if dbGrp == nil {
-		onceEDB.Do(func() {
-			dbGrp = &DBGrp{
-				db1: open(filepath.Join(dir, "db1")),
-				db2: open(filepath.Join(dir, "db2")),
-			}
-		})
+// open example dbs in registry, in-memory if dir is empty. dbs already open are kept.
+func InitDB(dir string) {
+	for _, name := range []string{db1Name, db2Name} {
+		if bh.DB(name) == nil {
+			_, err := bh.Open(name, dbOptions(dir, name))
+			lk.FailOnErr("%v", err)
+		}
 	}
-	return dbGrp
 }
 
 func CloseDB() {
-	dbGrp.Lock()
-	defer dbGrp.Unlock()
-
-	if dbGrp.db1 != nil {
-		lk.FailOnErr("%v", dbGrp.db1.Close())
-		dbGrp.db1 = nil
-	}
-	if dbGrp.db2 != nil {
-		lk.FailOnErr("%v", dbGrp.db2.Close())
-		dbGrp.db2 = nil
-	}
+	lk.FailOnErr("%v", bh.Close(db1Name))
+	lk.FailOnErr("%v", bh.Close(db2Name))
 }
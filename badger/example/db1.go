@@ -2,33 +2,36 @@ package example
 
 import (
 	"errors"
-	"fmt"
+	"iter"
 	"strings"
 
 	"github.com/dgraph-io/badger/v4"
 	bh "github.com/digisan/db-helper/badger"
+	"github.com/digisan/db-helper/badger/keys"
 	. "github.com/digisan/go-generics"
 	lk "github.com/digisan/logkit"
 )
 
-const (
-	_K = "^"
-	_V = "^"
-)
-
 // DB1 is an example for badger db usage
 type DB1 struct {
-	id        string
-	data      []string
-	fnDbStore func(*DB1) error
+	id   string
+	data []string
+}
+
+// db key of DB1 id, any symbol is allowed in id
+func DB1Key(id string) []byte {
+	return keys.Encode(keys.Str(id))
+}
+
+// prefix of DB1 keys whose id starts with s
+func db1Prefix(s string) []byte {
+	return keys.PrefixOpen(keys.Str(s))
 }
 
 func NewDB1(id string) *DB1 {
-	lk.FailOnErrWhen(strings.Contains(id, _K), "%v", fmt.Errorf("invalid symbol(%s) in id", _K))
 	return &DB1{
-		id:        id,
-		data:      []string{},
-		fnDbStore: bh.UpsertOneObject[DB1],
+		id:   id,
+		data: []string{},
 	}
 }
 
@@ -45,49 +48,69 @@ func (db1 DB1) String() string {
 ///////////////////////////////////////////////////////////////
 
 func (db1 *DB1) BadgerDB() *badger.DB {
-	return dbGrp.db1
+	return bh.DB(db1Name)
 }
 
 func (db1 *DB1) Key() []byte {
-	return []byte(db1.id)
+	return DB1Key(db1.id)
 }
 
 func (db1 *DB1) Marshal(at any) (forKey, forValue []byte) {
 	forKey = db1.Key()
 	lk.FailOnErrWhen(len(forKey) == 0, "%v", errors.New("invalid(empty) key for BadgerDB"))
-	forValue = []byte(fmt.Sprint(db1.data))
+	forValue, err := bh.JSON.Encode(db1.data)
+	lk.FailOnErr("%v", err)
 	return
 }
 
 func (db1 *DB1) Unmarshal(dbKey, dbVal []byte) (any, error) {
-	dbKeyStr := string(dbKey)
-	typeid := strings.Split(dbKeyStr, _K)
-	db1.id = typeid[0]
-	dbValStr := string(dbVal)
-	dbValStr = strings.TrimPrefix(dbValStr, "[")
-	dbValStr = strings.TrimSuffix(dbValStr, "]")
-	db1.data = strings.Split(dbValStr, " ")
-	db1.fnDbStore = bh.UpsertOneObject[DB1]
+	segs, err := keys.Decode(dbKey)
+	if err != nil {
+		return nil, err
+	}
+	db1.id = segs[0].Str
+	db1.data = []string{}
+	if err := bh.JSON.Decode(dbVal, &db1.data); err != nil {
+		return nil, err
+	}
 	return db1, nil
 }
 
 ///////////////////////////////////////////////////////////////
 
+// add items to stored data atomically, db1 is refreshed to stored result
 func (db1 *DB1) AddData(items ...string) error {
-	db1.data = append(db1.data, items...)
-	db1.data = Settify(db1.data...)
-	return db1.fnDbStore(db1)
+	stored, err := bh.ModifyObject(db1.Key(), func(d *DB1) (*DB1, error) {
+		d.id = db1.id
+		d.data = Settify(append(d.data, items...)...)
+		return d, nil
+	}, true)
+	if err != nil {
+		return err
+	}
+	db1.data = stored.data
+	return nil
 }
 
+// remove items from stored data atomically, db1 is refreshed to stored result
 func (db1 *DB1) RmData(items ...string) error {
-	FilterFast(&db1.data, func(i int, e string) bool {
-		return NotIn(e, items...)
-	})
-	return db1.fnDbStore(db1)
+	stored, err := bh.ModifyObject(db1.Key(), func(d *DB1) (*DB1, error) {
+		FilterFast(&d.data, func(i int, e string) bool {
+			return NotIn(e, items...)
+		})
+		return d, nil
+	}, false)
+	if err != nil {
+		return err
+	}
+	if stored != nil {
+		db1.data = stored.data
+	}
+	return nil
 }
 
 func GetDB1(id string) (*DB1, error) {
-	db1, err := bh.GetOneObject[DB1]([]byte(id))
+	db1, err := bh.GetOneObject[DB1](DB1Key(id))
 	if err != nil {
 		return nil, err
 	}
@@ -95,7 +118,7 @@ func GetDB1(id string) (*DB1, error) {
 }
 
 func GetDB1Data(id string) ([]string, error) {
-	db1, err := bh.GetOneObject[DB1]([]byte(id))
+	db1, err := bh.GetOneObject[DB1](DB1Key(id))
 	if err != nil {
 		return nil, err
 	}
@@ -106,25 +129,58 @@ func GetDB1Data(id string) ([]string, error) {
 }
 
 func GetDB1s(prefix string, filter func(*DB1) bool) ([]*DB1, error) {
-	db1s, err := bh.GetObjects([]byte(prefix), filter)
+	db1s, err := bh.GetObjects(db1Prefix(prefix), filter)
 	if err != nil {
 		return nil, err
 	}
 	return db1s, err
 }
 
+func IterDB1s(prefix string, filter func(*DB1) bool) iter.Seq2[*DB1, error] {
+	return bh.IterObjects(db1Prefix(prefix), filter)
+}
+
+func GetDB1Page(prefix string, filter func(*DB1) bool, size int, cursor []byte) ([]*DB1, []byte, error) {
+	return bh.GetObjectsPage(db1Prefix(prefix), filter, size, cursor)
+}
+
+func GetDB1sInRange(start, end string, filter func(*DB1) bool, limit int) ([]*DB1, error) {
+	endKey := []byte{}
+	if end != "" {
+		endKey = DB1Key(end)
+	}
+	return bh.GetObjectsInRange(DB1Key(start), endKey, filter, limit)
+}
+
+func GetDB1sDesc(prefix string, filter func(*DB1) bool) ([]*DB1, error) {
+	return bh.GetObjectsDesc(db1Prefix(prefix), filter)
+}
+
 func GetDB1First(prefix string, filter func(*DB1) bool) (*DB1, error) {
-	return bh.GetFirstObject([]byte(prefix), filter)
+	return bh.GetFirstObject(db1Prefix(prefix), filter)
+}
+
+func GetDB1Last(prefix string, filter func(*DB1) bool) (*DB1, error) {
+	return bh.GetLastObject(db1Prefix(prefix), filter)
 }
 
 func GetDB1Count(prefix string, filter func(*DB1) bool) (int, error) {
-	return bh.GetObjectCount([]byte(prefix), filter)
+	return bh.GetObjectCount(db1Prefix(prefix), filter)
 }
 
 func DelDB1First(prefix string) (int, error) {
-	return bh.DeleteFirstObject[DB1]([]byte(prefix))
+	return bh.DeleteFirstObject[DB1](db1Prefix(prefix))
+}
+
+func DelDB1Last(prefix string) (int, error) {
+	return bh.DeleteLastObject[DB1](db1Prefix(prefix))
+}
+
+func MoveDB1(oldID, newID string) (*DB1, error) {
+	moved, _, err := bh.MoveObject[DB1](DB1Key(oldID), DB1Key(newID))
+	return moved, err
 }
 
 func UpdateDB1First(prefix string, object *DB1) (int, error) {
-	return bh.UpdateFirstObject([]byte(prefix), object)
+	return bh.UpdateFirstObject(db1Prefix(prefix), object)
 }
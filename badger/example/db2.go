@@ -0,0 +1,76 @@
+package example
+
+import (
+	"fmt"
+
+	"github.com/dgraph-io/badger/v4"
+	bh "github.com/digisan/db-helper/badger"
+)
+
+// DB2 is an example for badger db usage with secondary indexes
+type DB2 struct {
+	ID    string
+	Name  string
+	Email string
+	Group string
+}
+
+var db2Adapter = bh.Adapter[DB2]{
+	Codec: bh.JSON,
+	Key:   func(db2 *DB2) []byte { return []byte(db2.ID) },
+}
+
+///////////////////////////////////////////////////////////////
+
+func (db2 *DB2) BadgerDB() *badger.DB {
+	return bh.DB(db2Name)
+}
+
+func (db2 *DB2) Key() []byte {
+	return db2Adapter.Key(db2)
+}
+
+func (db2 *DB2) Marshal(at any) (forKey, forValue []byte) {
+	return db2Adapter.Marshal(db2)
+}
+
+func (db2 *DB2) Unmarshal(dbKey, dbVal []byte) (any, error) {
+	return db2Adapter.Unmarshal(db2, dbKey, dbVal)
+}
+
+// DB2 keeps its key in value, bh.MoveObject sets new key by it
+func (db2 *DB2) SetKey(key []byte) {
+	db2.ID = string(key)
+}
+
+// DB2 takes auto-increment id from bh.InsertWithNewID
+func (db2 *DB2) SetID(id uint64) {
+	db2.ID = fmt.Sprintf("U%06d", id)
+}
+
+func (db2 *DB2) Indexes() map[string][]byte {
+	return map[string][]byte{
+		"email": []byte(db2.Email),
+		"group": []byte(db2.Group),
+	}
+}
+
+///////////////////////////////////////////////////////////////
+
+func GetDB2sByGroup(group string) ([]*DB2, error) {
+	return bh.GetObjectsByIndex[DB2]("group", []byte(group))
+}
+
+func GetDB2ByEmail(email string) (*DB2, error) {
+	db2s, err := bh.GetObjectsByIndex[DB2]("email", []byte(email))
+	if err != nil || len(db2s) == 0 {
+		return nil, err
+	}
+	return db2s[0], nil
+}
+
+// insert db2 with next id of 'user' sequence
+func InsertDB2(db2 *DB2) error {
+	_, err := bh.InsertWithNewID(db2, "user")
+	return err
+}
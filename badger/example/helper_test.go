@@ -1,9 +1,22 @@
 package example
 
 import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	bh "github.com/digisan/db-helper/badger"
+	"github.com/digisan/db-helper/badger/keys"
 )
 
 func TestTemp(t *testing.T) {
@@ -99,3 +112,1667 @@ func TestUpdate(t *testing.T) {
 	}
 
 }
+
+func TestIterDB1s(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+	useTempDB(t, db1Name)
+
+	for _, id := range []string{"AA", "AB", "AC", "AD"} {
+		if err := NewDB1(id).AddData("1", "2"); err != nil {
+			panic(err)
+		}
+	}
+
+	ids := []string{}
+	for db1, err := range IterDB1s("A", func(d *DB1) bool { return len(d.id) == 2 }) {
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(db1)
+		if ids = append(ids, db1.id); len(ids) == 2 {
+			break
+		}
+	}
+	fmt.Println("iterated:", ids)
+	if !slices.Equal(ids, []string{"AA", "AB"}) {
+		panic("AA, AB expected before break")
+	}
+
+	// break has closed read txn, prefix can be deleted right after
+	n, err := bh.DeleteObjects[DB1](db1Prefix("A"))
+	if err != nil {
+		panic(err)
+	}
+	if n != 4 {
+		panic("4 objects should be deleted after break")
+	}
+
+	// undecodable value is yielded as the last element
+	if err := NewDB1("BA").AddData("1"); err != nil {
+		panic(err)
+	}
+	err = bh.DB(db1Name).Update(func(txn *badger.Txn) error {
+		return txn.Set(DB1Key("BB"), []byte("not json"))
+	})
+	if err != nil {
+		panic(err)
+	}
+	if err := NewDB1("BC").AddData("1"); err != nil {
+		panic(err)
+	}
+	ids, errs := []string{}, []error{}
+	for db1, err := range IterDB1s("B", nil) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ids = append(ids, db1.id)
+	}
+	fmt.Println("iterated:", ids, errs)
+	if !slices.Equal(ids, []string{"BA"}) || len(errs) != 1 {
+		panic("BA then one error expected")
+	}
+}
+
+func TestGetDB1Page(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+
+	for _, id := range []string{"AA", "AB", "AC", "AD", "AE"} {
+		if err := NewDB1(id).AddData("1"); err != nil {
+			panic(err)
+		}
+	}
+
+	var cursor []byte
+	for {
+		page, next, err := GetDB1Page("A", nil, 2, cursor)
+		if err != nil {
+			panic(err)
+		}
+		for _, db1 := range page {
+			fmt.Println(db1.id)
+		}
+		fmt.Printf("next cursor: %q\n", next)
+		if next == nil {
+			break
+		}
+		cursor = next
+	}
+
+	// no cursor when nothing after a full page passes filter
+	page, next, err := GetDB1Page("A", func(db1 *DB1) bool { return db1.id < "AC" }, 2, nil)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("filtered: %d, next cursor: %q\n", len(page), next)
+	if len(page) != 2 || next != nil {
+		panic("full last page should have nil next cursor")
+	}
+}
+
+func TestDesc(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+	useTempDB(t, db1Name)
+
+	for _, id := range []string{"A", "AA", "AB", "AC", "B", "C"} {
+		if err := NewDB1(id).AddData("1"); err != nil {
+			panic(err)
+		}
+	}
+
+	db1s, err := GetDB1sDesc("", nil)
+	if err != nil {
+		panic(err)
+	}
+	if ids := db1IDs(db1s); !slices.Equal(ids, []string{"C", "B", "AC", "AB", "AA", "A"}) {
+		panic(fmt.Sprintf("all in descending order expected, got %v", ids))
+	}
+
+	// a key right at the end of prefix range "A" is not part of it, reverse scan MUST skip it
+	err = bh.DB(db1Name).Update(func(txn *badger.Txn) error {
+		end := db1Prefix("A")
+		end[len(end)-1]++
+		return txn.Set(end, []byte("not DB1"))
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	db1s, err = GetDB1sDesc("A", nil)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(db1IDs(db1s))
+	if ids := db1IDs(db1s); !slices.Equal(ids, []string{"AC", "AB", "AA", "A"}) {
+		panic(fmt.Sprintf("AC, AB, AA, A expected, got %v", ids))
+	}
+
+	fmt.Println("----------------------")
+
+	db1, err := GetDB1Last("A", nil)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("last:", db1.id)
+	if db1.id != "AC" {
+		panic("last of A should be AC")
+	}
+
+	db1, err = GetDB1Last("", nil)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("last of all:", db1.id)
+	if db1.id != "C" {
+		panic("last of all should be C")
+	}
+
+	fmt.Println("----------------------")
+
+	dn, err := DelDB1Last("A")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("deleted:", dn)
+	if dn != 1 {
+		panic("AC should be deleted")
+	}
+
+	pages := [][]string{}
+	var cursor []byte
+	for {
+		page, next, err := bh.GetObjectsPageDesc[DB1](db1Prefix("A"), nil, 2, cursor)
+		if err != nil {
+			panic(err)
+		}
+		pages = append(pages, db1IDs(page))
+		if next == nil {
+			break
+		}
+		cursor = next
+	}
+	fmt.Println("pages:", pages)
+	if len(pages) != 2 || !slices.Equal(pages[0], []string{"AB", "AA"}) || !slices.Equal(pages[1], []string{"A"}) {
+		panic("pages [AB AA] [A] expected")
+	}
+}
+
+// ids of db1s in order
+func db1IDs(db1s []*DB1) []string {
+	ids := []string{}
+	for _, db1 := range db1s {
+		ids = append(ids, db1.id)
+	}
+	return ids
+}
+
+func TestRange(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+	useTempDB(t, db1Name)
+
+	for _, id := range []string{"2024-01", "2024-03", "2024-06", "2024-07", "2024-09"} {
+		if err := NewDB1(id).AddData("1"); err != nil {
+			panic(err)
+		}
+	}
+
+	// start is inclusive, end is exclusive
+	db1s, err := GetDB1sInRange("2024-01", "2024-07", nil, 0)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(db1IDs(db1s))
+	if ids := db1IDs(db1s); !slices.Equal(ids, []string{"2024-01", "2024-03", "2024-06"}) {
+		panic(fmt.Sprintf("2024-01, 2024-03, 2024-06 expected, got %v", ids))
+	}
+
+	fmt.Println("----------------------")
+
+	db1s, err = GetDB1sInRange("2024-02", "", nil, 2)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(db1IDs(db1s))
+	if ids := db1IDs(db1s); !slices.Equal(ids, []string{"2024-03", "2024-06"}) {
+		panic(fmt.Sprintf("2024-03, 2024-06 expected, got %v", ids))
+	}
+
+	fmt.Println("----------------------")
+
+	n, err := bh.GetObjectCountInRange[DB1](DB1Key("2024-01"), DB1Key("2024-07"), nil, 0)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("count:", n)
+	if n != 3 {
+		panic("count 3 expected")
+	}
+
+	n, err = bh.DeleteObjectsInRange[DB1](DB1Key("2024-03"), DB1Key("2024-09"), 0)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("deleted:", n)
+	if n != 3 {
+		panic("2024-03, 2024-06, 2024-07 should be deleted")
+	}
+
+	m, err := bh.GetMapInRange[DB1](nil, nil, nil, 0)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("remains:", len(m))
+	db1s, err = GetDB1s("", nil)
+	if err != nil {
+		panic(err)
+	}
+	if ids := db1IDs(db1s); len(m) != 2 || !slices.Equal(ids, []string{"2024-01", "2024-09"}) {
+		panic(fmt.Sprintf("2024-01, 2024-09 should remain, got %v", ids))
+	}
+}
+
+func TestTTL(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+
+	db1 := NewDB1("session")
+	db1.data = []string{"token"}
+	if err := bh.UpsertOneObjectWith(db1, bh.WithTTL(2*time.Second), bh.WithUserMeta(0x01)); err != nil {
+		panic(err)
+	}
+
+	ttl, found, err := bh.GetObjectTTL[DB1](DB1Key("session"))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("ttl:", ttl, "found:", found)
+
+	time.Sleep(3 * time.Second)
+
+	db1, err = GetDB1("session")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("expired:", db1 == nil)
+}
+
+func TestTxn(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+	useTempDB(t, db1Name)
+
+	for _, id := range []string{"TA", "TB", "TC"} {
+		if err := NewDB1(id).AddData(id); err != nil {
+			panic(err)
+		}
+	}
+
+	err := bh.WithTxn(bh.DB(db1Name), func(tx *bh.Tx) error {
+		a, err := bh.TxGetOneObject[DB1](tx, DB1Key("TA"))
+		if err != nil {
+			return err
+		}
+		b, err := bh.TxGetOneObject[DB1](tx, DB1Key("TB"))
+		if err != nil {
+			return err
+		}
+		b.data = append(b.data, a.data...)
+		if err := bh.TxUpsertOneObject(tx, b); err != nil {
+			return err
+		}
+		_, err = bh.TxDeleteOneObject[DB1](tx, DB1Key("TC"))
+		return err
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	db1s, err := GetDB1s("T", nil)
+	if err != nil {
+		panic(err)
+	}
+	for _, db1 := range db1s {
+		fmt.Println(db1)
+	}
+	if ids := db1IDs(db1s); !slices.Equal(ids, []string{"TA", "TB"}) || !slices.Equal(db1s[1].data, []string{"TB", "TA"}) {
+		panic("TB should have TA data merged, TC should be deleted")
+	}
+
+	// error of fn discards all its writes
+	errAbort := errors.New("abort")
+	err = bh.WithTxn(bh.DB(db1Name), func(tx *bh.Tx) error {
+		if err := bh.TxUpsertOneObject(tx, NewDB1("TD")); err != nil {
+			return err
+		}
+		if _, err := bh.TxDeleteOneObject[DB1](tx, DB1Key("TA")); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		panic("fn error should be returned")
+	}
+	if db1s, err = GetDB1s("T", nil); err != nil {
+		panic(err)
+	}
+	if ids := db1IDs(db1s); !slices.Equal(ids, []string{"TA", "TB"}) {
+		panic(fmt.Sprintf("nothing should be committed, got %v", ids))
+	}
+
+	// a conflicting write during first run makes fn run again on fresh data
+	runs := 0
+	err = bh.WithTxn(bh.DB(db1Name), func(tx *bh.Tx) error {
+		runs++
+		a, err := bh.TxGetOneObject[DB1](tx, DB1Key("TA"))
+		if err != nil {
+			return err
+		}
+		if runs == 1 {
+			if err := NewDB1("TA").AddData("X"); err != nil {
+				return err
+			}
+		}
+		d := NewDB1("TD")
+		d.data = a.data
+		return bh.TxUpsertOneObject(tx, d)
+	})
+	if err != nil {
+		panic(err)
+	}
+	data, err := GetDB1Data("TD")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("runs:", runs, data)
+	if runs != 2 || !slices.Equal(data, []string{"TA", "X"}) {
+		panic("fn should be retried once on conflict and see TA with X")
+	}
+}
+
+func TestModify(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+
+	// 10 writers on one key conflict more often than default retries allow
+	defer func(retries int) { bh.TxnRetries = retries }(bh.TxnRetries)
+	bh.TxnRetries = 20
+
+	wg := sync.WaitGroup{}
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := NewDB1("M").AddData(fmt.Sprint(i)); err != nil {
+				panic(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, err := GetDB1Data("M")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(len(data), data)
+	for i := range 10 {
+		if !slices.Contains(data, fmt.Sprint(i)) {
+			panic(fmt.Sprintf("update %d is lost", i))
+		}
+	}
+
+	db1 := NewDB1("M")
+	if err := db1.RmData("0", "1"); err != nil {
+		panic(err)
+	}
+	fmt.Println(db1)
+
+	// read-modify-write keeps TTL & user meta
+	session := NewDB1("MS")
+	if err := bh.UpsertOneObjectWith(session, bh.WithTTL(time.Hour), bh.WithUserMeta(0x01)); err != nil {
+		panic(err)
+	}
+	if err := session.AddData("token"); err != nil {
+		panic(err)
+	}
+	ttl, found, err := bh.GetObjectTTL[DB1](DB1Key("MS"))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("ttl after modify:", ttl, found)
+	if !found || ttl <= 0 || ttl > time.Hour {
+		panic("TTL should be kept by ModifyObject")
+	}
+	err = bh.DB(db1Name).View(func(txn *badger.Txn) error {
+		item, err := txn.Get(DB1Key("MS"))
+		if err != nil {
+			return err
+		}
+		if item.UserMeta() != 0x01 {
+			return errors.New("user meta should be kept by ModifyObject")
+		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// nil result of fn is refused
+	if _, err := bh.ModifyObject(DB1Key("MS"), func(*DB1) (*DB1, error) { return nil, nil }, false); err == nil {
+		panic("nil modified object should be refused")
+	}
+}
+
+func TestMove(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+
+	if err := NewDB1("MA").AddData("1", "2"); err != nil {
+		panic(err)
+	}
+	if err := NewDB1("MB").AddData("3"); err != nil {
+		panic(err)
+	}
+
+	moved, err := MoveDB1("MA", "MC")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("moved:", moved)
+
+	db1 := NewDB1("MD")
+	db1.data = []string{"4"}
+	replaced, err := bh.ReplaceObject(DB1Key("MB"), db1)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("replaced:", replaced)
+
+	db1s, err := GetDB1s("M", nil)
+	if err != nil {
+		panic(err)
+	}
+	for _, db1 := range db1s {
+		fmt.Println(db1.id, db1.data)
+	}
+
+	// DB2 keeps its key in value, it is moved by SetKey with TTL & indexes following
+	db2 := &DB2{ID: "mv1", Name: "Mover", Email: "mover@example.com", Group: "move"}
+	if err := bh.UpsertOneObjectWith(db2, bh.WithTTL(time.Hour)); err != nil {
+		panic(err)
+	}
+	moved2, _, err := bh.MoveObject[DB2]([]byte("mv1"), []byte("mv2"))
+	if err != nil {
+		panic(err)
+	}
+	byEmail, err := GetDB2ByEmail("mover@example.com")
+	if err != nil {
+		panic(err)
+	}
+	ttl, _, err := bh.GetObjectTTL[DB2]([]byte("mv2"))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("moved DB2:", moved2.ID, byEmail.ID, ttl > 0)
+	if moved2.ID != "mv2" || byEmail.ID != "mv2" || ttl == 0 {
+		panic("moved DB2 should be at mv2 with its index & TTL")
+	}
+}
+
+// rebind example dbs to empty ones in temp dirs, so test does not depend on ./data left by former runs
+func useTempDB(t *testing.T, names ...string) {
+	for _, name := range names {
+		if err := bh.Close(name); err != nil {
+			panic(err)
+		}
+		if _, err := bh.Open(name, bh.DBOptions{Dir: t.TempDir()}); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestVersion(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+	useTempDB(t, db1Name)
+
+	db1 := NewDB1("V")
+	db1.data = []string{"1"}
+	if err := bh.InsertIfAbsent(db1); err != nil {
+		panic(err)
+	}
+	err := bh.InsertIfAbsent(db1)
+	fmt.Println("insert again:", err)
+	if !errors.Is(err, bh.ErrVersionConflict) {
+		panic("insert on existing key should conflict")
+	}
+
+	db1, ver, err := bh.GetOneObjectVersion[DB1](DB1Key("V"))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("version:", ver)
+
+	db1.data = append(db1.data, "2")
+	if err := bh.UpsertIfVersion(db1, ver); err != nil {
+		panic(err)
+	}
+
+	db1.data = append(db1.data, "3")
+	err = bh.UpsertIfVersion(db1, ver)
+	fmt.Println("stale write:", err)
+	if !errors.Is(err, bh.ErrVersionConflict) {
+		panic("stale write should conflict")
+	}
+
+	vs, err := bh.GetObjectsVersion[DB1](db1Prefix("V"), nil)
+	if err != nil {
+		panic(err)
+	}
+	for _, v := range vs {
+		fmt.Println(v.Object.id, v.Object.data, v.Version)
+	}
+}
+
+func TestIndex(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+	useTempDB(t, db2Name)
+
+	err := bh.UpsertObjects(
+		&DB2{ID: "u1", Name: "Alice", Email: "alice@a.com", Group: "admin"},
+		&DB2{ID: "u2", Name: "Bob", Email: "bob@a.com", Group: "dev"},
+		&DB2{ID: "u3", Name: "Carol", Email: "carol@a.com", Group: "dev"},
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	// move Bob to admin
+	if err := bh.UpsertOneObject(&DB2{ID: "u2", Name: "Bob", Email: "bob@a.com", Group: "admin"}); err != nil {
+		panic(err)
+	}
+	if _, err := bh.DeleteOneObject[DB2]([]byte("u3")); err != nil {
+		panic(err)
+	}
+
+	groupNames := func(group string) []string {
+		db2s, err := GetDB2sByGroup(group)
+		if err != nil {
+			panic(err)
+		}
+		names := []string{}
+		for _, db2 := range db2s {
+			names = append(names, db2.Name)
+		}
+		fmt.Println(group+":", names)
+		return names
+	}
+	if names := groupNames("admin"); !slices.Equal(names, []string{"Alice", "Bob"}) {
+		panic("admin should be Alice & Bob")
+	}
+	if names := groupNames("dev"); len(names) != 0 {
+		panic("dev should be empty after Bob moved and Carol deleted")
+	}
+
+	db2, err := GetDB2ByEmail("alice@a.com")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("by email:", db2.Name)
+	if db2 == nil || db2.Name != "Alice" {
+		panic("Alice expected by email")
+	}
+	if db2, err = GetDB2ByEmail("carol@a.com"); err != nil || db2 != nil {
+		panic("deleted Carol should not be found by email")
+	}
+
+	// index entries are not seen by scans
+	all, err := bh.GetObjects[DB2](nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("all:", len(all))
+	if len(all) != 2 {
+		panic("only u1 & u2 should be scanned")
+	}
+
+	// raw rewrite leaves old index entry stale, it MUST not resolve to changed object
+	_, err = bh.Migrate(bh.DB(db2Name), []bh.Migration{{
+		Version: 1,
+		Name:    "rename group",
+		Prefix:  []byte("u"),
+		Rewrite: func(key, val []byte) ([]byte, []byte, error) {
+			one := &DB2{}
+			if err := bh.JSON.Decode(val, one); err != nil {
+				return nil, nil, err
+			}
+			if one.Group == "admin" {
+				one.Group = "staff"
+			}
+			val, err := bh.JSON.Encode(one)
+			return key, val, err
+		},
+	}}, false)
+	if err != nil {
+		panic(err)
+	}
+	if all, err = bh.GetObjects[DB2](nil, nil); err != nil {
+		panic(err)
+	}
+	if err := bh.UpsertObjects(all...); err != nil {
+		panic(err)
+	}
+	staff, err := GetDB2sByGroup("staff")
+	if err != nil {
+		panic(err)
+	}
+	if names := groupNames("admin"); len(names) != 0 || len(staff) != 2 {
+		panic("admin should be empty, staff should be Alice & Bob after migration and upsert")
+	}
+
+	// indexed batch beyond one transaction limit is committed in chunks
+	if err := bh.Close(db2Name); err != nil {
+		panic(err)
+	}
+	_, err = bh.Open(db2Name, bh.DBOptions{
+		Dir: t.TempDir(),
+		Tune: func(opt badger.Options) badger.Options {
+			return opt.WithMemTableSize(1 << 20).WithValueThreshold(1 << 10)
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	many := []*DB2{}
+	for i := range 3000 {
+		many = append(many, &DB2{ID: fmt.Sprintf("big%04d", i), Email: fmt.Sprintf("big%04d@example.com", i), Group: "big"})
+	}
+	if err := bh.UpsertObjects(many...); err != nil {
+		panic(err)
+	}
+	big, err := GetDB2sByGroup("big")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("big batch:", len(big))
+	if len(big) != 3000 {
+		panic("all of big batch should be stored & indexed")
+	}
+}
+
+func TestCodec(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+
+	if err := NewDB1("CD").AddData("with space", "x"); err != nil {
+		panic(err)
+	}
+	data, err := GetDB1Data("CD")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%q\n", data)
+
+	for _, codec := range []bh.Codec{bh.JSON, bh.Gob, bh.MsgPack, bh.CBOR} {
+		adapter := bh.Adapter[DB2]{
+			Codec:  codec,
+			Key:    func(db2 *DB2) []byte { return []byte(db2.ID) },
+			SetKey: func(db2 *DB2, key []byte) { db2.ID = string(key) },
+		}
+		k, v := adapter.Marshal(&DB2{ID: "c1", Name: "Name With Space", Group: "g"})
+		db2 := &DB2{}
+		if _, err := adapter.Unmarshal(db2, k, v); err != nil {
+			panic(err)
+		}
+		fmt.Printf("%T %+v\n", codec, *db2)
+	}
+}
+
+func TestKeys(t *testing.T) {
+
+	ts := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	ks := [][]byte{
+		keys.Encode(keys.Str("user"), keys.Int(-5)),
+		keys.Encode(keys.Str("user"), keys.Int(3)),
+		keys.Encode(keys.Str("user"), keys.Int(300)),
+		keys.Encode(keys.Str("user\x00a"), keys.Time(ts)),
+		keys.Encode(keys.Str("user^x"), keys.Uint(1)),
+	}
+	fmt.Println("sorted:", slices.IsSortedFunc(ks, bytes.Compare))
+	if !slices.IsSortedFunc(ks, bytes.Compare) {
+		panic("encoded keys should keep segment order")
+	}
+
+	for _, k := range ks {
+		segs, err := keys.Decode(k)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%q\n", segs)
+	}
+
+	InitDB("./data")
+	defer CloseDB()
+	useTempDB(t, db1Name)
+
+	for _, id := range []string{"a^b", "a b", "a\x00c", "b"} {
+		if err := NewDB1(id).AddData("1"); err != nil {
+			panic(err)
+		}
+	}
+	db1s, err := GetDB1s("a", nil)
+	if err != nil {
+		panic(err)
+	}
+	for _, db1 := range db1s {
+		fmt.Printf("%q\n", db1.id)
+	}
+	if ids := db1IDs(db1s); !slices.Equal(ids, []string{"a\x00c", "a b", "a^b"}) {
+		panic(fmt.Sprintf("ids with any symbol should sort by bytes under prefix, got %q", ids))
+	}
+}
+
+func TestKeyOnly(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+
+	for _, id := range []string{"KA", "KB", "KC"} {
+		if err := NewDB1(id).AddData("1"); err != nil {
+			panic(err)
+		}
+	}
+
+	ks, err := bh.ListKeys[DB1](db1Prefix("K"))
+	if err != nil {
+		panic(err)
+	}
+	for _, k := range ks {
+		segs, _ := keys.Decode(k)
+		fmt.Println(segs)
+	}
+
+	n, err := bh.CountKeys[DB1](db1Prefix("K"))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("count:", n)
+
+	found, err := bh.Exists[DB1](DB1Key("KA"))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("KA exists:", found)
+
+	founds, err := bh.ExistsMany[DB1](DB1Key("KB"), DB1Key("KX"), DB1Key("KC"))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(founds)
+}
+
+func TestParallel(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+	useTempDB(t, db1Name)
+
+	for i := range 100 {
+		if err := NewDB1(fmt.Sprintf("P%03d", i)).AddData(fmt.Sprint(i)); err != nil {
+			panic(err)
+		}
+	}
+
+	n := 0
+	err := bh.ScanParallel(context.Background(), db1Prefix("P"), nil, bh.ParallelOptions{NumGo: 4}, func(d *DB1) error {
+		n++
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("unordered:", n)
+	if n != 100 {
+		panic("all 100 objects should be scanned")
+	}
+
+	ids := []string{}
+	err = bh.ScanParallel(context.Background(), db1Prefix("P"), func(d *DB1) bool { return d.id < "P010" }, bh.ParallelOptions{NumGo: 4, Ordered: true}, func(d *DB1) error {
+		ids = append(ids, d.id)
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("ordered:", ids)
+	want := []string{}
+	for i := range 10 {
+		want = append(want, fmt.Sprintf("P%03d", i))
+	}
+	if !slices.Equal(ids, want) {
+		panic("P000..P009 expected in key order")
+	}
+
+	cObj, cErr := bh.StreamObjects[DB1](context.Background(), db1Prefix("P"), nil, bh.ParallelOptions{})
+	n = 0
+	for range cObj {
+		n++
+	}
+	if err := <-cErr; err != nil {
+		panic(err)
+	}
+	fmt.Println("channel:", n)
+	if n != 100 {
+		panic("all 100 objects should be streamed")
+	}
+
+	// first error of fn stops scan and is returned
+	errStop := errors.New("stop")
+	for _, ordered := range []bool{false, true} {
+		err = bh.ScanParallel(context.Background(), db1Prefix("P"), nil, bh.ParallelOptions{NumGo: 4, Ordered: ordered}, func(d *DB1) error {
+			if d.id == "P050" {
+				return errStop
+			}
+			return nil
+		})
+		fmt.Println("ordered:", ordered, "error:", err)
+		if !errors.Is(err, errStop) {
+			panic("fn error should be returned")
+		}
+	}
+}
+
+func TestBackup(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+	useTempDB(t, db1Name)
+
+	dir := t.TempDir()
+
+	for _, id := range []string{"BA", "BB"} {
+		if err := NewDB1(id).AddData("1"); err != nil {
+			panic(err)
+		}
+	}
+	entry, err := bh.BackupFull(bh.DB(db1Name), dir)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%+v\n", entry)
+
+	if err := NewDB1("BC").AddData("2"); err != nil {
+		panic(err)
+	}
+	if _, err := bh.DeleteOneObject[DB1](DB1Key("BA")); err != nil {
+		panic(err)
+	}
+	entry, err = bh.BackupIncremental(bh.DB(db1Name), dir)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%+v\n", entry)
+
+	restoreDB, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		panic(err)
+	}
+	defer restoreDB.Close()
+
+	restored, err := bh.Restore(restoreDB, dir)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("restored:", len(restored))
+
+	err = restoreDB.View(func(txn *badger.Txn) error {
+		for id, want := range map[string]bool{"BA": false, "BB": true, "BC": true} {
+			_, err := txn.Get(DB1Key(id))
+			if errors.Is(err, badger.ErrKeyNotFound) == want {
+				return fmt.Errorf("%s restored: %v, expected: %v", id, !want, want)
+			}
+			if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	events := make(chan string, 100)
+	go func() {
+		done <- bh.Watch(ctx, db1Prefix("W"), func(ev bh.Event[*DB1]) error {
+			if ev.Type == bh.EventPut {
+				events <- fmt.Sprintf("%v %s %v", ev.Type, ev.Object.id, ev.Object.data)
+			} else {
+				events <- fmt.Sprintf("%v %q", ev.Type, ev.Key)
+			}
+			return nil
+		})
+	}()
+
+	// write probe W0 until it is seen, then subscription is surely running
+	for seen := false; !seen; {
+		if err := NewDB1("W0").AddData("probe"); err != nil {
+			panic(err)
+		}
+		select {
+		case <-events:
+			seen = true
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	if err := NewDB1("W1").AddData("a", "b"); err != nil {
+		panic(err)
+	}
+	if err := NewDB1("X1").AddData("x"); err != nil {
+		panic(err)
+	}
+	if _, err := bh.DeleteOneObject[DB1](DB1Key("W1")); err != nil {
+		panic(err)
+	}
+
+	// events come in commit order, X1 would come before W1 delete if it were watched
+	got := []string{}
+	for len(got) < 2 {
+		select {
+		case ev := <-events:
+			if !strings.Contains(ev, "W0") {
+				got = append(got, ev)
+			}
+		case <-time.After(5 * time.Second):
+			panic("events of W1 are not delivered")
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		panic(err)
+	}
+	fmt.Println(strings.Join(got, "\n"))
+	if !strings.HasPrefix(got[0], "put W1") || !strings.HasPrefix(got[1], "delete") {
+		panic("expected put and delete events of W1 only")
+	}
+}
+
+func TestDeleteChunked(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+	useTempDB(t, db1Name, db2Name)
+
+	for i := range 50 {
+		if err := NewDB1(fmt.Sprintf("C%03d", i)).AddData(fmt.Sprint(i % 2)); err != nil {
+			panic(err)
+		}
+	}
+	if err := NewDB1("D0").AddData("kept"); err != nil {
+		panic(err)
+	}
+
+	steps := []int{}
+	n, err := bh.DeleteObjectsChunked(context.Background(), db1Prefix("C"), func(d *DB1) bool {
+		return d.data[0] == "1"
+	}, 7, func(deleted int) {
+		steps = append(steps, deleted)
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("filtered:", n, steps)
+	if n != 25 || !slices.Equal(steps, []int{7, 14, 21, 25}) {
+		panic("25 odd C should be deleted in chunks of 7")
+	}
+
+	n, err = bh.DeleteObjectsChunked[DB1](context.Background(), db1Prefix("C"), nil, 0, nil)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("deleted rest:", n)
+	if n != 25 {
+		panic("25 even C should be deleted")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	n, err = bh.DeleteObjectsChunked[DB1](ctx, db1Prefix("D"), nil, 0, nil)
+	fmt.Println("cancelled:", n, err)
+	if n != 0 || !errors.Is(err, context.Canceled) {
+		panic("nothing should be deleted after ctx is cancelled")
+	}
+
+	cnt, err := GetDB1Count("C", nil)
+	if err != nil {
+		panic(err)
+	}
+	d0, err := GetDB1("D0")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("left:", cnt, d0 != nil)
+	if cnt != 0 || d0 == nil {
+		panic("all C should be deleted, D0 should be kept")
+	}
+
+	// drop without count
+	for i := range 10 {
+		if err := NewDB1(fmt.Sprintf("E%03d", i)).AddData("1"); err != nil {
+			panic(err)
+		}
+	}
+	if err := bh.DropObjects[DB1](db1Prefix("E")); err != nil {
+		panic(err)
+	}
+	if cnt, err = GetDB1Count("E", nil); err != nil || cnt != 0 {
+		panic("all E should be dropped")
+	}
+	if err := bh.DropObjects[DB1](nil); err == nil {
+		panic("empty prefix should be refused")
+	}
+	if err := bh.DropObjects[DB2]([]byte("u")); err == nil {
+		panic("Indexed type should be refused")
+	}
+}
+
+func TestDeleteReturning(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+
+	for _, id := range []string{"R1", "R2", "R3", "R4"} {
+		if err := NewDB1(id).AddData("data of " + id); err != nil {
+			panic(err)
+		}
+	}
+
+	n, one, err := bh.DeleteOneObjectReturning[DB1](DB1Key("R1"))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(n, one)
+
+	n, one, err = bh.DeleteOneObjectReturning[DB1](DB1Key("R1"))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("absent:", n, one == nil)
+
+	n, one, err = bh.DeleteLastObjectReturning[DB1](db1Prefix("R"))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(n, one)
+
+	n, all, err := bh.DeleteObjectsReturning[DB1](db1Prefix("R"))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(n, all)
+	if n != 2 || len(all) != 2 || all[0].id != "R2" || all[1].id != "R3" {
+		panic("R2 & R3 should be deleted and returned")
+	}
+}
+
+func TestSequence(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+	useTempDB(t, db1Name, db2Name)
+
+	for _, name := range []string{"Ann", "Bob", "Cat"} {
+		db2 := &DB2{Name: name, Email: strings.ToLower(name) + "@example.com", Group: "seq"}
+		if err := InsertDB2(db2); err != nil {
+			panic(err)
+		}
+		fmt.Println(db2.ID, db2.Name)
+	}
+
+	db2s, err := GetDB2sByGroup("seq")
+	if err != nil {
+		panic(err)
+	}
+	if len(db2s) != 3 || db2s[0].ID != "U000001" {
+		panic("3 users with ids from U000001 expected")
+	}
+
+	a := bh.NewIDAllocator(bh.DB(db1Name), 2)
+	for range 3 {
+		id1, err := a.Next("a")
+		if err != nil {
+			panic(err)
+		}
+		id2, err := a.Next("b")
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(id1, id2)
+	}
+	if err := a.Release(); err != nil {
+		panic(err)
+	}
+	a = bh.NewIDAllocator(bh.DB(db1Name), 2)
+	defer a.Release()
+	id, err := a.Next("a")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("after release:", id)
+	if id != 4 {
+		panic("released ids should continue from 4")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+
+	if err := NewDB1("G1").AddData("kept"); err != nil {
+		panic(err)
+	}
+	fmt.Println(bh.DBNames())
+
+	_, err := bh.Open(db1Name, bh.DBOptions{})
+	fmt.Println("open again:", err)
+
+	if _, err := bh.Reopen(db1Name); err != nil {
+		panic(err)
+	}
+	data, err := GetDB1Data("G1")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("after reopen:", data)
+
+	mem, err := bh.Open("mem", bh.DBOptions{InMemory: true})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("in-memory:", mem.Opts().InMemory, bh.DB("mem") == mem)
+	if err := bh.Close("mem"); err != nil {
+		panic(err)
+	}
+	fmt.Println(bh.DBNames(), bh.DB("mem") == nil)
+
+	// other dbs stay reachable while a db is migrated at opening
+	dir := t.TempDir()
+	slow, err := bh.Open("slow", bh.DBOptions{Dir: dir})
+	if err != nil {
+		panic(err)
+	}
+	if err := slow.Update(func(txn *badger.Txn) error { return txn.Set([]byte("k"), []byte("v")) }); err != nil {
+		panic(err)
+	}
+	if err := bh.Close("slow"); err != nil {
+		panic(err)
+	}
+	started, release := make(chan struct{}), make(chan struct{})
+	t.Cleanup(func() { bh.UnregisterMigrations("slow") })
+	err = bh.RegisterMigrations("slow", bh.Migration{
+		Version: 1,
+		Name:    "wait",
+		Rewrite: func(key, val []byte) ([]byte, []byte, error) {
+			close(started)
+			<-release
+			return key, val, nil
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	opened := make(chan error)
+	go func() {
+		_, err := bh.Open("slow", bh.DBOptions{Dir: dir})
+		opened <- err
+	}()
+	<-started
+	reached := make(chan bool)
+	go func() { reached <- bh.DB(db1Name) != nil && bh.DB("slow") == nil }()
+	select {
+	case ok := <-reached:
+		if !ok {
+			panic("db1 should be reachable, slow should not be ready while migrating")
+		}
+	case <-time.After(5 * time.Second):
+		panic("registry should not be locked while migrating")
+	}
+	close(release)
+	if err := <-opened; err != nil {
+		panic(err)
+	}
+	fmt.Println("migrated at open:", bh.DB("slow") != nil)
+	if err := bh.Close("slow"); err != nil {
+		panic(err)
+	}
+}
+
+func TestEncryption(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+
+	dir := t.TempDir()
+	oldKey, err := bh.NewEncryptionKey(32)
+	if err != nil {
+		panic(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "old.key")
+	if err := os.WriteFile(keyFile, oldKey, 0o600); err != nil {
+		panic(err)
+	}
+	if oldKey, err = bh.LoadEncryptionKey(keyFile); err != nil {
+		panic(err)
+	}
+
+	// rebind example db1 to an encrypted db in temp dir
+	if err := bh.Close(db1Name); err != nil {
+		panic(err)
+	}
+	if _, err := bh.Open(db1Name, bh.DBOptions{Dir: dir, EncryptionKey: oldKey}); err != nil {
+		panic(err)
+	}
+	if err := NewDB1("E1").AddData("secret"); err != nil {
+		panic(err)
+	}
+	err = bh.RotateEncryptionKey(dir, oldKey, oldKey)
+	fmt.Println("rotate while open:", err)
+	if err == nil {
+		panic("rotation of an open db should fail")
+	}
+	if err := bh.Close(db1Name); err != nil {
+		panic(err)
+	}
+
+	newKey, err := bh.NewEncryptionKey(16)
+	if err != nil {
+		panic(err)
+	}
+	t.Setenv("BH_TEST_KEY", hex.EncodeToString(newKey))
+	if newKey, err = bh.LoadEncryptionKeyEnv("BH_TEST_KEY"); err != nil {
+		panic(err)
+	}
+	if err := bh.RotateEncryptionKey(dir, oldKey, newKey); err != nil {
+		panic(err)
+	}
+
+	_, err = bh.Open(db1Name, bh.DBOptions{Dir: dir, EncryptionKey: oldKey})
+	fmt.Println("open by old key:", err)
+	if err == nil {
+		panic("rotated db should not open by old key")
+	}
+
+	if _, err := bh.Open(db1Name, bh.DBOptions{Dir: dir, EncryptionKey: newKey, KeyRotation: time.Hour}); err != nil {
+		panic(err)
+	}
+	data, err := GetDB1Data("E1")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("open by new key:", data)
+	if len(data) != 1 || data[0] != "secret" {
+		panic("data should survive key rotation")
+	}
+}
+
+func TestGC(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+
+	// rebind example db1 to a db with background GC and small value log files
+	if err := bh.Close(db1Name); err != nil {
+		panic(err)
+	}
+	_, err := bh.Open(db1Name, bh.DBOptions{
+		Dir: t.TempDir(),
+		GC:  bh.GCOptions{Interval: 50 * time.Millisecond, DiscardRatio: 0.1},
+		Tune: func(opt badger.Options) badger.Options {
+			return opt.WithValueThreshold(1 << 10).WithValueLogFileSize(1 << 20)
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	big := strings.Repeat("x", 16<<10)
+	for round := range 3 {
+		for i := range 100 {
+			db1 := NewDB1(fmt.Sprintf("GC%03d", i))
+			if err := db1.AddData(fmt.Sprint(round, big)); err != nil {
+				panic(err)
+			}
+		}
+	}
+	if _, err := bh.DeleteObjects[DB1](db1Prefix("GC")); err != nil {
+		panic(err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	stats := bh.GC(db1Name).Stats()
+	fmt.Printf("runs: %d, rewritten: %d, reclaimed: %d, err: %v\n", stats.Runs, stats.Rewritten, stats.Reclaimed, stats.LastErr)
+	if stats.Runs == 0 || stats.LastErr != nil {
+		panic("background GC should have run without error")
+	}
+
+	stats = bh.GC(db1Name).RunOnce()
+	fmt.Printf("manual run: %d files, %d bytes in %v\n", stats.LastRewritten, stats.LastReclaimed, stats.LastDuration)
+
+	// GC is stopped by closing
+	if err := bh.Close(db1Name); err != nil {
+		panic(err)
+	}
+	fmt.Println(bh.GC(db1Name) == nil)
+}
+
+func TestStats(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+
+	for _, id := range []string{"SA1", "SA2", "SB1"} {
+		if err := NewDB1(id).AddData(strings.Repeat(id, 10)); err != nil {
+			panic(err)
+		}
+	}
+	db1 := NewDB1("SC1")
+	db1.data = []string{"short lived"}
+	if err := bh.UpsertOneObjectWith(db1, bh.WithTTL(time.Second)); err != nil {
+		panic(err)
+	}
+	time.Sleep(1100 * time.Millisecond)
+
+	// DB1 keys are 0x01 + id + 0x00, group them by kind byte and first 2 id letters
+	report, err := bh.StorageStats(bh.DB(db1Name), bh.StatsOptions{Prefix: db1Prefix("S"), Depth: 3})
+	if err != nil {
+		panic(err)
+	}
+	if err := report.Table(os.Stdout); err != nil {
+		panic(err)
+	}
+	if len(report.Groups) != 3 || report.Groups[0].Keys != 2 || report.Groups[2].Expired != 1 || report.Total.Keys != 3 {
+		panic("groups SA (2 keys), SB (1 key), SC (1 expired) expected")
+	}
+
+	// DB2 keys are plain ids, indexes are reported as one meta group
+	if err := InsertDB2(&DB2{Name: "Stat", Email: "stat@example.com", Group: "stats"}); err != nil {
+		panic(err)
+	}
+	report, err = bh.StorageStats(bh.DB(db2Name), bh.StatsOptions{Depth: 1, Meta: true})
+	if err != nil {
+		panic(err)
+	}
+	if err := report.JSON(os.Stdout); err != nil {
+		panic(err)
+	}
+}
+
+func TestJSONL(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+	useTempDB(t, db1Name, db2Name)
+
+	for _, name := range []string{"Joe", "Jim"} {
+		if err := InsertDB2(&DB2{Name: name, Email: strings.ToLower(name) + "@jsonl.com", Group: "jsonl"}); err != nil {
+			panic(err)
+		}
+	}
+	buf := &bytes.Buffer{}
+	n, err := bh.ExportObjects(buf, nil, func(db2 *DB2) bool { return db2.Group == "jsonl" })
+	if err != nil {
+		panic(err)
+	}
+	fmt.Print(n, " typed:\n", buf.String())
+
+	for _, email := range []string{"joe@jsonl.com", "jim@jsonl.com"} {
+		one, err := GetDB2ByEmail(email)
+		if err != nil {
+			panic(err)
+		}
+		if _, err := bh.DeleteOneObject[DB2](one.Key()); err != nil {
+			panic(err)
+		}
+	}
+	if n, err = bh.ImportObjects[DB2](buf, 1); err != nil {
+		panic(err)
+	}
+	jim, err := GetDB2ByEmail("jim@jsonl.com")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("imported:", n, jim.Name)
+	if n != 2 || jim.Name != "Jim" {
+		panic("typed records should be imported with indexes")
+	}
+
+	for _, id := range []string{"J1", "J2"} {
+		if err := NewDB1(id).AddData("raw " + id); err != nil {
+			panic(err)
+		}
+	}
+	buf.Reset()
+	if n, err = bh.ExportRaw(bh.DB(db1Name), buf, db1Prefix("J")); err != nil {
+		panic(err)
+	}
+	fmt.Print(n, " raw:\n", buf.String())
+	raw := buf.String()
+
+	if _, err := bh.DeleteObjects[DB1](db1Prefix("J")); err != nil {
+		panic(err)
+	}
+	if n, err = bh.ImportRaw(bh.DB(db1Name), buf); err != nil {
+		panic(err)
+	}
+	data, err := GetDB1Data("J2")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("imported:", n, data)
+
+	// raw records can be imported as typed objects by Unmarshal
+	if n, err = bh.ImportObjects[DB1](strings.NewReader(raw), 0); err != nil {
+		panic(err)
+	}
+	fmt.Println("imported typed:", n)
+
+	// typed export of DB1 without exported fields is imported by Unmarshal of stored value
+	buf.Reset()
+	if n, err = bh.ExportObjects[DB1](buf, db1Prefix("J"), nil); err != nil {
+		panic(err)
+	}
+	if _, err := bh.DeleteObjects[DB1](db1Prefix("J")); err != nil {
+		panic(err)
+	}
+	if n, err = bh.ImportObjects[DB1](buf, 0); err != nil {
+		panic(err)
+	}
+	if data, err = GetDB1Data("J2"); err != nil {
+		panic(err)
+	}
+	fmt.Println("imported typed DB1:", n, data)
+	if n != 2 || !slices.Equal(data, []string{"raw J2"}) {
+		panic("typed DB1 records should round trip")
+	}
+
+	// whole db raw export carries index entries & id sequence into another db
+	buf.Reset()
+	if n, err = bh.ExportRaw(bh.DB(db2Name), buf, nil); err != nil {
+		panic(err)
+	}
+	useTempDB(t, db2Name)
+	if n, err = bh.ImportRaw(bh.DB(db2Name), buf); err != nil {
+		panic(err)
+	}
+	jim, err = GetDB2ByEmail("jim@jsonl.com")
+	if err != nil {
+		panic(err)
+	}
+	db2 := &DB2{Name: "Jon", Email: "jon@jsonl.com", Group: "jsonl"}
+	if err := InsertDB2(db2); err != nil {
+		panic(err)
+	}
+	fmt.Println("imported whole db:", n, jim != nil, db2.ID)
+	// sequence record keeps end of its lease, so new ids may skip some but never reuse one
+	if jim == nil || db2.ID <= "U000002" {
+		panic("index & sequence should be restored by whole db import")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+
+	InitDB("./data")
+	defer CloseDB()
+
+	// rebind example db1 to an empty db holding DB1 values of legacy layout "a,b,c"
+	if err := bh.Close(db1Name); err != nil {
+		panic(err)
+	}
+	db, err := bh.Open(db1Name, bh.DBOptions{Dir: t.TempDir()})
+	if err != nil {
+		panic(err)
+	}
+	err = db.Update(func(txn *badger.Txn) error {
+		for id, csv := range map[string]string{"M2": "c", "M3": ""} {
+			if err := txn.Set(DB1Key(id), []byte(csv)); err != nil {
+				return err
+			}
+		}
+		// TTL & user meta MUST survive migrations
+		return txn.SetEntry(badger.NewEntry(DB1Key("M1"), []byte("a,b")).WithTTL(time.Hour).WithMeta(7))
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	migrations := []bh.Migration{
+		{
+			Version: 2,
+			Name:    "rename M to N, drop empty",
+			Prefix:  db1Prefix("M"),
+			Rewrite: func(key, val []byte) ([]byte, []byte, error) {
+				if string(val) == "[]" {
+					return nil, nil, nil
+				}
+				segs, err := keys.Decode(key)
+				if err != nil {
+					return nil, nil, err
+				}
+				return DB1Key("N" + strings.TrimPrefix(segs[0].Str, "M")), val, nil
+			},
+		},
+		{
+			Version: 1,
+			Name:    "csv to json",
+			Prefix:  db1Prefix("M"),
+			Rewrite: func(key, val []byte) ([]byte, []byte, error) {
+				data := []string{}
+				if len(val) > 0 {
+					data = strings.Split(string(val), ",")
+				}
+				json, err := bh.JSON.Encode(data)
+				return key, json, err
+			},
+		},
+	}
+
+	report, err := bh.Migrate(db, migrations, true)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("dry run: %+v\n", *report)
+	dryResults := report.Results
+
+	report, err = bh.Migrate(db, migrations, false)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("migrated: %+v\n", *report)
+	if !slices.Equal(dryResults, report.Results) {
+		panic("dry run results MUST be the same as migrated ones")
+	}
+	if r := report.Results[1]; r.Changed != 2 || r.Deleted != 1 {
+		panic("version 2 MUST change 2 keys and delete 1")
+	}
+	err = db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(DB1Key("N1"))
+		if err != nil {
+			return err
+		}
+		if item.ExpiresAt() == 0 || item.UserMeta() != 7 {
+			return errors.New("TTL & user meta of N1 are lost")
+		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	db1s, err := GetDB1s("", nil)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(db1s)
+	if ver, err := bh.SchemaVersion(db); err != nil || ver != 2 || len(db1s) != 2 || db1s[0].id != "N1" {
+		panic("N1 & N2 expected at schema version 2")
+	}
+
+	// pending migrations run when db is opened
+	t.Cleanup(func() { bh.UnregisterMigrations(db1Name) })
+	err = bh.RegisterMigrations(db1Name, append(migrations, bh.Migration{
+		Version: 3,
+		Name:    "sort data",
+		Prefix:  db1Prefix("N"),
+		Rewrite: func(key, val []byte) ([]byte, []byte, error) {
+			data := []string{}
+			if err := bh.JSON.Decode(val, &data); err != nil {
+				return nil, nil, err
+			}
+			slices.Reverse(data)
+			json, err := bh.JSON.Encode(data)
+			return key, json, err
+		},
+	})...)
+	if err != nil {
+		panic(err)
+	}
+	if db, err = bh.Reopen(db1Name); err != nil {
+		panic(err)
+	}
+	ver, err := bh.SchemaVersion(db)
+	if err != nil {
+		panic(err)
+	}
+	data, err := GetDB1Data("N1")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("version:", ver, data)
+	if ver != 3 || data[0] != "b" {
+		panic("migration 3 should run at reopen")
+	}
+}
@@ -0,0 +1,142 @@
+package badgerhelper
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+type GCOptions struct {
+	Interval     time.Duration // wait between GC runs, no background GC if 0
+	DiscardRatio float64       // rewrite a value log file if this part of it can be discarded, 0.5 if 0
+}
+
+type GCStats struct {
+	Runs          int           // finished GC runs, each one rewrites files until nothing more to rewrite
+	Rewritten     int           // value log files rewritten by all runs
+	Reclaimed     int64         // value log bytes reclaimed by all runs
+	LastRun       time.Time     // start of last run
+	LastDuration  time.Duration // duration of last run
+	LastRewritten int           // value log files rewritten by last run
+	LastReclaimed int64         // value log bytes reclaimed by last run
+	LastErr       error         // error which stopped last run, nil if it just ran out of files to rewrite
+}
+
+// value log garbage collector of one db, runs in background when Interval is set
+type GCRunner struct {
+	run   sync.Mutex // one run at a time
+	mtx   sync.Mutex // guards stats
+	db    *badger.DB
+	opts  GCOptions
+	stats GCStats
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+// start background GC of db, call Stop before closing db. in-memory db has no value log, so no GC.
+func StartGC(db *badger.DB, opts GCOptions) *GCRunner {
+	if opts.DiscardRatio <= 0 {
+		opts.DiscardRatio = 0.5
+	}
+	g := &GCRunner{
+		db:   db,
+		opts: opts,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if opts.Interval <= 0 || db.Opts().InMemory {
+		close(g.done)
+		return g
+	}
+	go func() {
+		defer close(g.done)
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-g.stop:
+				return
+			case <-ticker.C:
+				g.RunOnce()
+			}
+		}
+	}()
+	return g
+}
+
+// run GC now, repeating RunValueLogGC while it rewrites files. stats of this run are returned.
+func (g *GCRunner) RunOnce() GCStats {
+	g.run.Lock()
+	defer g.run.Unlock()
+
+	start := time.Now()
+	before := vlogSize(g.db)
+	rewritten := 0
+	var err error
+	for err == nil && !g.stopped() {
+		if err = g.db.RunValueLogGC(g.opts.DiscardRatio); err == nil {
+			rewritten++
+		}
+	}
+	if errors.Is(err, badger.ErrNoRewrite) {
+		err = nil
+	}
+	reclaimed := max(before-vlogSize(g.db), 0)
+
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	g.stats.Runs++
+	g.stats.Rewritten += rewritten
+	g.stats.Reclaimed += reclaimed
+	g.stats.LastRun = start
+	g.stats.LastDuration = time.Since(start)
+	g.stats.LastRewritten = rewritten
+	g.stats.LastReclaimed = reclaimed
+	g.stats.LastErr = err
+	return g.stats
+}
+
+func (g *GCRunner) Stats() GCStats {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	return g.stats
+}
+
+func (g *GCRunner) stopped() bool {
+	select {
+	case <-g.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop background GC and wait for a running GC to finish
+func (g *GCRunner) Stop() {
+	g.once.Do(func() {
+		close(g.stop)
+	})
+	<-g.done
+}
+
+// total size of value log files. db.Size is only refreshed every minute, so files are measured here.
+func vlogSize(db *badger.DB) int64 {
+	dir := db.Opts().ValueDir
+	if dir == "" {
+		return 0
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.vlog"))
+	var size int64
+	for _, file := range files {
+		if fi, err := os.Stat(file); err == nil {
+			size += fi.Size()
+		}
+	}
+	return size
+}
@@ -20,37 +20,34 @@ type PtrDbAccessible[T any] interface {
 }
 
 // one object with fixed key
-func GetOneObject[V any, T PtrDbAccessible[V]](key []byte) (T, error) {
-	var (
-		found = false
-		rt    = T(new(V))
-		err   = rt.BadgerDB().View(func(txn *badger.Txn) error {
-			opts := badger.DefaultIteratorOptions
-			it := txn.NewIterator(opts)
-			defer it.Close()
+func GetOneObject[V any, T PtrDbAccessible[V]](key []byte) (rt T, err error) {
+	err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
+		rt, err = getOneObject[V, T](txn, key)
+		return err
+	})
+	return rt, err
+}
 
-			itemProc := func(item *badger.Item) error {
-				if bytes.Equal(key, item.Key()) {
-					if err := item.Value(func(val []byte) error {
-						_, err := rt.Unmarshal(key, val)
-						found = true
-						return err
-					}); err != nil {
-						return err
-					}
-				}
-				return nil
-			}
-			if it.Seek(key); it.Valid() {
-				return itemProc(it.Item())
-			}
-			return nil
-		})
-	)
-	if !found {
+// point lookup without iterator, so it can be used while a read-write txn is iterating
+func getOneObject[V any, T PtrDbAccessible[V]](txn *badger.Txn, key []byte) (T, error) {
+	if len(key) == 0 {
+		return nil, nil
+	}
+	item, err := txn.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
 		return nil, err
 	}
-	return rt, err
+	rt := T(new(V))
+	if err := item.Value(func(val []byte) error {
+		_, err := rt.Unmarshal(key, val)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return rt, nil
 }
 
 // use Unmarshal returned data as map-value, filter key is []byte type
@@ -58,8 +55,7 @@ func GetMap[V any, T PtrDbAccessible[V]](prefix []byte, filter func([]byte, any)
 	var (
 		rt  = make(map[string]any)
 		err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
-			opts := badger.DefaultIteratorOptions
-			it := txn.NewIterator(opts)
+			it := txn.NewIterator(scanOptions(prefix, false))
 			defer it.Close()
 
 			itemProc := func(item *badger.Item) error {
@@ -75,17 +71,12 @@ func GetMap[V any, T PtrDbAccessible[V]](prefix []byte, filter func([]byte, any)
 					return nil
 				})
 			}
-			if len(prefix) == 0 {
-				for it.Rewind(); it.Valid(); it.Next() {
-					if err := itemProc(it.Item()); err != nil {
-						return err
-					}
+			for seekPrefix(it, prefix, false); it.ValidForPrefix(prefix); it.Next() {
+				if hidden(prefix, it.Item().Key()) {
+					continue
 				}
-			} else {
-				for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-					if err := itemProc(it.Item()); err != nil {
-						return err
-					}
+				if err := itemProc(it.Item()); err != nil {
+					return err
 				}
 			}
 			return nil
@@ -95,92 +86,107 @@ func GetMap[V any, T PtrDbAccessible[V]](prefix []byte, filter func([]byte, any)
 }
 
 // all objects if prefix is nil or empty
-func GetObjects[V any, T PtrDbAccessible[V]](prefix []byte, filter func(T) bool) ([]T, error) {
-	var (
-		rt  = []T{}
-		err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
-			opts := badger.DefaultIteratorOptions
-			it := txn.NewIterator(opts)
-			defer it.Close()
+func GetObjects[V any, T PtrDbAccessible[V]](prefix []byte, filter func(T) bool) (rt []T, err error) {
+	err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
+		rt, err = getObjects[V, T](txn, prefix, filter, false)
+		return err
+	})
+	return rt, err
+}
 
-			itemProc := func(item *badger.Item) error {
-				return item.Value(func(val []byte) error {
-					one := T(new(V))
-					if _, err := one.Unmarshal(item.Key(), val); err != nil {
-						return err
-					}
-					if filter == nil || filter(one) {
-						rt = append(rt, one)
-					}
-					return nil
-				})
+// all objects in descending key order if prefix is nil or empty
+func GetObjectsDesc[V any, T PtrDbAccessible[V]](prefix []byte, filter func(T) bool) (rt []T, err error) {
+	err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
+		rt, err = getObjects[V, T](txn, prefix, filter, true)
+		return err
+	})
+	return rt, err
+}
+
+func getObjects[V any, T PtrDbAccessible[V]](txn *badger.Txn, prefix []byte, filter func(T) bool, reverse bool) ([]T, error) {
+	it := txn.NewIterator(scanOptions(prefix, reverse))
+	defer it.Close()
+
+	rt := []T{}
+	itemProc := func(item *badger.Item) error {
+		return item.Value(func(val []byte) error {
+			one := T(new(V))
+			if _, err := one.Unmarshal(item.Key(), val); err != nil {
+				return err
 			}
-			if len(prefix) == 0 {
-				for it.Rewind(); it.Valid(); it.Next() {
-					if err := itemProc(it.Item()); err != nil {
-						return err
-					}
-				}
-			} else {
-				for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-					if err := itemProc(it.Item()); err != nil {
-						return err
-					}
-				}
+			if filter == nil || filter(one) {
+				rt = append(rt, one)
 			}
 			return nil
 		})
-	)
-	return rt, err
+	}
+	for seekPrefix(it, prefix, reverse); it.ValidForPrefix(prefix); it.Next() {
+		if hidden(prefix, it.Item().Key()) {
+			continue
+		}
+		if err := itemProc(it.Item()); err != nil {
+			return nil, err
+		}
+	}
+	return rt, nil
 }
 
-func GetObjectCount[V any, T PtrDbAccessible[V]](prefix []byte, filter func(T) bool) (int, error) {
-	var (
-		n   = 0
-		err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
-			opts := badger.DefaultIteratorOptions
-			it := txn.NewIterator(opts)
-			defer it.Close()
+func GetObjectCount[V any, T PtrDbAccessible[V]](prefix []byte, filter func(T) bool) (n int, err error) {
+	err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
+		n, err = getObjectCount[V, T](txn, prefix, filter)
+		return err
+	})
+	return n, err
+}
 
-			itemProc := func(item *badger.Item) error {
-				return item.Value(func(val []byte) error {
-					one := T(new(V))
-					if _, err := one.Unmarshal(item.Key(), val); err != nil {
-						n = 0
-						return err
-					}
-					if filter == nil || filter(one) {
-						n++
-					}
-					return nil
-				})
+func getObjectCount[V any, T PtrDbAccessible[V]](txn *badger.Txn, prefix []byte, filter func(T) bool) (int, error) {
+	// without filter, no need to decode any value
+	if filter == nil {
+		return countKeys(txn, prefix), nil
+	}
+
+	it := txn.NewIterator(scanOptions(prefix, false))
+	defer it.Close()
+
+	n := 0
+	itemProc := func(item *badger.Item) error {
+		return item.Value(func(val []byte) error {
+			one := T(new(V))
+			if _, err := one.Unmarshal(item.Key(), val); err != nil {
+				return err
 			}
-			if len(prefix) == 0 {
-				for it.Rewind(); it.Valid(); it.Next() {
-					if err := itemProc(it.Item()); err != nil {
-						return err
-					}
-				}
-			} else {
-				for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-					if err := itemProc(it.Item()); err != nil {
-						return err
-					}
-				}
+			if filter == nil || filter(one) {
+				n++
 			}
 			return nil
 		})
-	)
-	return n, err
+	}
+	for seekPrefix(it, prefix, false); it.ValidForPrefix(prefix); it.Next() {
+		if hidden(prefix, it.Item().Key()) {
+			continue
+		}
+		if err := itemProc(it.Item()); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
 }
 
 func GetFirstObject[V any, T PtrDbAccessible[V]](prefix []byte, filter func(T) bool) (T, error) {
+	return getFirstObject[V, T](prefix, filter, false)
+}
+
+// the object with the greatest key under prefix, which passes filter
+func GetLastObject[V any, T PtrDbAccessible[V]](prefix []byte, filter func(T) bool) (T, error) {
+	return getFirstObject[V, T](prefix, filter, true)
+}
+
+func getFirstObject[V any, T PtrDbAccessible[V]](prefix []byte, filter func(T) bool, reverse bool) (T, error) {
 	var (
 		found = false
 		rt    = T(new(V))
 		err   = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
-			opts := badger.DefaultIteratorOptions
-			it := txn.NewIterator(opts)
+			it := txn.NewIterator(scanOptions(prefix, reverse))
 			defer it.Close()
 
 			itemProc := func(item *badger.Item) error {
@@ -196,23 +202,15 @@ func GetFirstObject[V any, T PtrDbAccessible[V]](prefix []byte, filter func(T) b
 					return nil
 				})
 			}
-			if len(prefix) == 0 {
-				for it.Rewind(); it.Valid(); it.Next() {
-					if err := itemProc(it.Item()); err != nil {
-						return err
-					}
-					if found {
-						break
-					}
+			for seekPrefix(it, prefix, reverse); it.ValidForPrefix(prefix); it.Next() {
+				if hidden(prefix, it.Item().Key()) {
+					continue
 				}
-			} else {
-				for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-					if err := itemProc(it.Item()); err != nil {
-						return err
-					}
-					if found {
-						break
-					}
+				if err := itemProc(it.Item()); err != nil {
+					return err
+				}
+				if found {
+					break
 				}
 			}
 			return nil
@@ -229,19 +227,52 @@ func GetFirstObject[V any, T PtrDbAccessible[V]](prefix []byte, filter func(T) b
 // update or insert one object
 func UpsertOneObject[V any, T PtrDbAccessible[V]](object T) error {
 	return object.BadgerDB().Update(func(txn *badger.Txn) error {
-		return txn.Set(object.Marshal(nil))
+		return upsertOneObject[V, T](txn, object)
 	})
 }
 
+func upsertOneObject[V any, T PtrDbAccessible[V]](txn *badger.Txn, object T, opts ...EntryOption) error {
+	k, v := object.Marshal(nil)
+	if isIndexed[V, T]() {
+		if err := unindexKey[V, T](txn, k); err != nil {
+			return err
+		}
+		if err := addIndexes(txn, k, object, opts...); err != nil {
+			return err
+		}
+	}
+	return txn.SetEntry(newEntry(k, v, opts...))
+}
+
 // update or insert part object at specific area
 func UpsertPartObject[V any, T PtrDbAccessible[V]](object T, at any) error {
 	return object.BadgerDB().Update(func(txn *badger.Txn) error {
-		return txn.Set(object.Marshal(at))
+		k, v := object.Marshal(at)
+		if !isIndexed[V, T]() {
+			return txn.Set(k, v)
+		}
+		if err := unindexKey[V, T](txn, k); err != nil {
+			return err
+		}
+		if err := txn.Set(k, v); err != nil {
+			return err
+		}
+		// index on the whole stored object after part update
+		stored, err := getOneObject[V, T](txn, k)
+		if err != nil {
+			return err
+		}
+		return addIndexes(txn, k, stored)
 	})
 }
 
-// update or insert many objects
+// update or insert many objects.
+// indexed objects are written by transactions instead of write batch, to maintain index entries.
 func UpsertObjects[V any, T PtrDbAccessible[V]](objects ...T) error {
+	if isIndexed[V, T]() {
+		return upsertIndexedObjects[V, T](objects)
+	}
+
 	wb := T(new(V)).BadgerDB().NewWriteBatch()
 	defer wb.Cancel()
 
@@ -253,62 +284,155 @@ func UpsertObjects[V any, T PtrDbAccessible[V]](objects ...T) error {
 	return wb.Flush()
 }
 
+// write indexed objects in transactions as big as possible.
+// like write batch, objects committed before an error stay stored.
+func upsertIndexedObjects[V any, T PtrDbAccessible[V]](objects []T, opts ...EntryOption) error {
+	_, err := updateEach(T(new(V)).BadgerDB(), objects, func(txn *badger.Txn, object T) error {
+		return upsertOneObject[V, T](txn, object, opts...)
+	})
+	return err
+}
+
 // -------------------------------------------------------------------- //
 
 // delete one object
 func DeleteOneObject[V any, T PtrDbAccessible[V]](key []byte) (n int, err error) {
-	return n, T(new(V)).BadgerDB().Update(func(txn *badger.Txn) error {
-		opts := badger.DefaultIteratorOptions
-		it := txn.NewIterator(opts)
-		defer it.Close()
+	err = T(new(V)).BadgerDB().Update(func(txn *badger.Txn) error {
+		n, err = deleteOneObject[V, T](txn, key, nil)
+		return err
+	})
+	return n, err
+}
 
-		if it.Seek(key); it.Valid() {
-			if item := it.Item(); bytes.Equal(key, item.Key()) {
-				if err = txn.Delete(item.KeyCopy(nil)); err == nil {
-					n++
-				}
-			}
-		}
+// delete one object, return deleted count, original object (nil if absent)
+func DeleteOneObjectReturning[V any, T PtrDbAccessible[V]](key []byte) (n int, deleted T, err error) {
+	taken := []T{}
+	err = T(new(V)).BadgerDB().Update(func(txn *badger.Txn) error {
+		n, err = deleteOneObject[V, T](txn, key, &taken)
 		return err
 	})
+	if err != nil || len(taken) == 0 {
+		return 0, nil, err
+	}
+	return n, taken[0], nil
+}
+
+func deleteOneObject[V any, T PtrDbAccessible[V]](txn *badger.Txn, key []byte, taken *[]T) (n int, err error) {
+	if len(key) == 0 {
+		return 0, nil
+	}
+	item, err := txn.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err = takeItem[V, T](txn, item, taken); err != nil {
+		return 0, err
+	}
+	return 1, nil
 }
 
-// delete multiple objects
+// delete multiple objects in one transaction, see DeleteObjectsChunked for big prefixes
 func DeleteObjects[V any, T PtrDbAccessible[V]](prefix []byte) (n int, err error) {
-	return n, T(new(V)).BadgerDB().Update(func(txn *badger.Txn) error {
-		opts := badger.DefaultIteratorOptions
-		it := txn.NewIterator(opts)
-		defer it.Close()
+	err = T(new(V)).BadgerDB().Update(func(txn *badger.Txn) error {
+		n, err = deleteObjects[V, T](txn, prefix, nil)
+		return err
+	})
+	return n, err
+}
 
-		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-			if err = txn.Delete(it.Item().KeyCopy(nil)); err == nil {
-				n++
-			} else {
-				break
-			}
-		}
+// delete multiple objects in one transaction, return deleted count, original objects
+func DeleteObjectsReturning[V any, T PtrDbAccessible[V]](prefix []byte) (n int, deleted []T, err error) {
+	deleted = []T{}
+	err = T(new(V)).BadgerDB().Update(func(txn *badger.Txn) error {
+		n, err = deleteObjects[V, T](txn, prefix, &deleted)
 		return err
 	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return n, deleted, nil
+}
+
+func deleteObjects[V any, T PtrDbAccessible[V]](txn *badger.Txn, prefix []byte, taken *[]T) (n int, err error) {
+	opts := badger.DefaultIteratorOptions
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		if hidden(prefix, it.Item().Key()) {
+			continue
+		}
+		if err = takeItem[V, T](txn, it.Item(), taken); err == nil {
+			n++
+		} else {
+			break
+		}
+	}
+	return n, err
 }
 
 func DeleteFirstObject[V any, T PtrDbAccessible[V]](prefix []byte) (n int, err error) {
-	return n, T(new(V)).BadgerDB().Update(func(txn *badger.Txn) error {
-		opts := badger.DefaultIteratorOptions
-		it := txn.NewIterator(opts)
+	return deleteFirstObject[V, T](prefix, false, nil)
+}
+
+// delete the object with the greatest key under prefix
+func DeleteLastObject[V any, T PtrDbAccessible[V]](prefix []byte) (n int, err error) {
+	return deleteFirstObject[V, T](prefix, true, nil)
+}
+
+// delete the object with the least key under prefix, return deleted count, original object (nil if absent)
+func DeleteFirstObjectReturning[V any, T PtrDbAccessible[V]](prefix []byte) (int, T, error) {
+	return deleteFirstObjectReturning[V, T](prefix, false)
+}
+
+// delete the object with the greatest key under prefix, return deleted count, original object (nil if absent)
+func DeleteLastObjectReturning[V any, T PtrDbAccessible[V]](prefix []byte) (int, T, error) {
+	return deleteFirstObjectReturning[V, T](prefix, true)
+}
+
+func deleteFirstObjectReturning[V any, T PtrDbAccessible[V]](prefix []byte, reverse bool) (int, T, error) {
+	taken := []T{}
+	n, err := deleteFirstObject[V, T](prefix, reverse, &taken)
+	if err != nil || len(taken) == 0 {
+		return 0, nil, err
+	}
+	return n, taken[0], nil
+}
+
+func deleteFirstObject[V any, T PtrDbAccessible[V]](prefix []byte, reverse bool, taken *[]T) (n int, err error) {
+	err = T(new(V)).BadgerDB().Update(func(txn *badger.Txn) error {
+		it := txn.NewIterator(scanOptions(prefix, reverse))
 		defer it.Close()
 
-		if it.Seek(prefix); it.ValidForPrefix(prefix) {
-			if err = txn.Delete(it.Item().KeyCopy(nil)); err == nil {
+		for seekPrefix(it, prefix, reverse); it.ValidForPrefix(prefix); it.Next() {
+			if hidden(prefix, it.Item().Key()) {
+				continue
+			}
+			if err = takeItem[V, T](txn, it.Item(), taken); err == nil {
 				n++
 			}
+			break
 		}
 		return err
 	})
+	return n, err
 }
 
 // -------------------------------------------------------------------- //
 
 func UpdateFirstObject[V any, T PtrDbAccessible[V]](prefix []byte, object T) (n int, err error) {
+	return updateFirstObject[V, T](prefix, object, false)
+}
+
+// replace the object with the greatest key under prefix
+func UpdateLastObject[V any, T PtrDbAccessible[V]](prefix []byte, object T) (n int, err error) {
+	return updateFirstObject[V, T](prefix, object, true)
+}
+
+func updateFirstObject[V any, T PtrDbAccessible[V]](prefix []byte, object T, reverse bool) (n int, err error) {
 
 	if len(object.Key()) == 0 {
 		return 0, errors.New("object.Key CANNOT be empty")
@@ -320,17 +444,23 @@ func UpdateFirstObject[V any, T PtrDbAccessible[V]](prefix []byte, object T) (n
 		return 0, errors.New("object.Key MUST start with input prefix")
 	}
 
-	return n, T(new(V)).BadgerDB().Update(func(txn *badger.Txn) error {
-		opts := badger.DefaultIteratorOptions
-		it := txn.NewIterator(opts)
+	err = T(new(V)).BadgerDB().Update(func(txn *badger.Txn) error {
+		it := txn.NewIterator(scanOptions(prefix, reverse))
 		defer it.Close()
 
-		if it.Seek(prefix); it.ValidForPrefix(prefix) {
-			if err = txn.Delete(it.Item().KeyCopy(nil)); err == nil {
-				n++
-				return UpsertOneObject(object)
+		for seekPrefix(it, prefix, reverse); it.ValidForPrefix(prefix); it.Next() {
+			if hidden(prefix, it.Item().Key()) {
+				continue
 			}
+			// delete and insert in the same transaction, they commit or fail together
+			if err = deleteItem[V, T](txn, it.Item()); err == nil {
+				if err = upsertOneObject[V, T](txn, object); err == nil {
+					n++
+				}
+			}
+			break
 		}
 		return err
 	})
+	return n, err
 }
@@ -0,0 +1,157 @@
+package badgerhelper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// optional for DbAccessible type, declares secondary indexes of object as index name -> index value.
+// empty index value is not indexed. index entries are maintained by upsert & delete helpers
+// in the same transaction as object itself, then GetObjectsByIndex resolves objects through them.
+type Indexed interface {
+	Indexes() map[string][]byte
+}
+
+// index entries key: reserved 'idx' space, index name, length-prefixed index value, then object key
+func indexPrefix(name string, value []byte) []byte {
+	prefix := metaKey([]byte("idx"), []byte(name), nil)
+	prefix = binary.AppendUvarint(prefix, uint64(len(value)))
+	return append(prefix, value...)
+}
+
+func isIndexed[V any, T PtrDbAccessible[V]]() bool {
+	_, ok := any(T(new(V))).(Indexed)
+	return ok
+}
+
+// write index entries for object stored at key, entry options (e.g. TTL) follow object
+func addIndexes(txn *badger.Txn, key []byte, object any, opts ...EntryOption) error {
+	idx, ok := object.(Indexed)
+	if !ok {
+		return nil
+	}
+	for name, value := range idx.Indexes() {
+		if len(value) == 0 {
+			continue
+		}
+		if err := txn.SetEntry(newEntry(append(indexPrefix(name, value), key...), []byte{}, opts...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove index entries of object stored at key
+func removeIndexes(txn *badger.Txn, key []byte, object any) error {
+	idx, ok := object.(Indexed)
+	if !ok {
+		return nil
+	}
+	for name, value := range idx.Indexes() {
+		if len(value) == 0 {
+			continue
+		}
+		if err := txn.Delete(append(indexPrefix(name, value), key...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove index entries of stored value at key, nothing to do if T is not indexed
+func unindex[V any, T PtrDbAccessible[V]](txn *badger.Txn, key, val []byte) error {
+	if !isIndexed[V, T]() {
+		return nil
+	}
+	old := T(new(V))
+	if _, err := old.Unmarshal(key, val); err != nil {
+		return err
+	}
+	return removeIndexes(txn, key, old)
+}
+
+// remove index entries of object currently stored at key, if any
+func unindexKey[V any, T PtrDbAccessible[V]](txn *badger.Txn, key []byte) error {
+	if !isIndexed[V, T]() {
+		return nil
+	}
+	item, err := txn.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return item.Value(func(val []byte) error {
+		return unindex[V, T](txn, key, val)
+	})
+}
+
+// delete stored item along with its index entries
+func deleteItem[V any, T PtrDbAccessible[V]](txn *badger.Txn, item *badger.Item) error {
+	key := item.KeyCopy(nil)
+	if isIndexed[V, T]() {
+		if err := item.Value(func(val []byte) error {
+			return unindex[V, T](txn, key, val)
+		}); err != nil {
+			return err
+		}
+	}
+	return txn.Delete(key)
+}
+
+// delete item like deleteItem, and append its decoded object to taken if taken is not nil
+func takeItem[V any, T PtrDbAccessible[V]](txn *badger.Txn, item *badger.Item, taken *[]T) error {
+	if taken == nil {
+		return deleteItem[V, T](txn, item)
+	}
+	one := T(new(V))
+	if err := item.Value(func(val []byte) error {
+		_, err := one.Unmarshal(item.KeyCopy(nil), val)
+		return err
+	}); err != nil {
+		return err
+	}
+	if err := deleteItem[V, T](txn, item); err != nil {
+		return err
+	}
+	*taken = append(*taken, one)
+	return nil
+}
+
+// objects whose index 'name' has exactly value, stale index entries not matching stored object are skipped
+func GetObjectsByIndex[V any, T PtrDbAccessible[V]](name string, value []byte) ([]T, error) {
+	if len(value) == 0 {
+		return nil, errors.New("index value CANNOT be empty")
+	}
+	if !isIndexed[V, T]() {
+		return nil, errors.New("object MUST implement Indexed")
+	}
+	var (
+		rt  = []T{}
+		err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
+			prefix := indexPrefix(name, value)
+			opts := badger.DefaultIteratorOptions
+			opts.Prefix = prefix
+			opts.PrefetchValues = false
+			it := txn.NewIterator(opts)
+			defer it.Close()
+
+			for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+				one, err := getOneObject[V, T](txn, it.Item().Key()[len(prefix):])
+				if err != nil {
+					return err
+				}
+				// object may be expired ahead of its index entry, or changed by a raw write without index maintenance
+				if one != nil && bytes.Equal(any(one).(Indexed).Indexes()[name], value) {
+					rt = append(rt, one)
+				}
+			}
+			return nil
+		})
+	)
+	return rt, err
+}
@@ -0,0 +1,46 @@
+package badgerhelper
+
+import "iter"
+
+// stream objects one by one, all objects if prefix is nil or empty.
+// read transaction is only held while caller ranges, break stops scan and closes iterator.
+// if any error happens, it is yielded with nil object as the last element.
+func IterObjects[V any, T PtrDbAccessible[V]](prefix []byte, filter func(T) bool) iter.Seq2[T, error] {
+	return iterObjects[V, T](prefix, filter, false)
+}
+
+// stream objects one by one in descending key order, all objects if prefix is nil or empty.
+func IterObjectsDesc[V any, T PtrDbAccessible[V]](prefix []byte, filter func(T) bool) iter.Seq2[T, error] {
+	return iterObjects[V, T](prefix, filter, true)
+}
+
+func iterObjects[V any, T PtrDbAccessible[V]](prefix []byte, filter func(T) bool, reverse bool) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		txn := T(new(V)).BadgerDB().NewTransaction(false)
+		defer txn.Discard()
+
+		it := txn.NewIterator(scanOptions(prefix, reverse))
+		defer it.Close()
+
+		for seekPrefix(it, prefix, reverse); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			if hidden(prefix, item.Key()) {
+				continue
+			}
+			one := T(new(V))
+			if err := item.Value(func(val []byte) error {
+				_, err := one.Unmarshal(item.Key(), val)
+				return err
+			}); err != nil {
+				yield(nil, err)
+				return
+			}
+			if filter != nil && !filter(one) {
+				continue
+			}
+			if !yield(one, nil) {
+				return
+			}
+		}
+	}
+}
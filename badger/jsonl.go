@@ -0,0 +1,211 @@
+package badgerhelper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// objects upserted in one UpsertObjects call by ImportObjects when batch is not given
+const DefaultImportBatch = 1000
+
+// one line of JSON Lines export. typed export fills Object & Value, raw export fills Value.
+type ExportRecord struct {
+	Key       []byte          `json:"key"`                  // base64 in JSON
+	KeyText   string          `json:"key_text,omitempty"`   // printable key for reading, ignored by import
+	Object    json.RawMessage `json:"object,omitempty"`     // JSON of object decoded by Unmarshal, for reading
+	Value     []byte          `json:"value,omitempty"`      // stored value, base64 in JSON
+	ExpiresAt uint64          `json:"expires_at,omitempty"` // unix time TTL ends, raw export only
+	UserMeta  byte            `json:"user_meta,omitempty"`  // raw export only
+}
+
+// write each object under prefix (all objects if prefix is nil or empty) as one JSON line.
+// objects are decoded by Unmarshal then encoded by encoding/json for reading, their stored value is written as well,
+// so ImportObjects rebuilds them by Unmarshal even if T has no exported fields.
+func ExportObjects[V any, T PtrDbAccessible[V]](w io.Writer, prefix []byte, filter func(T) bool) (n int, err error) {
+	enc := json.NewEncoder(w)
+	err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(scanOptions(prefix, false))
+		defer it.Close()
+
+		for seekPrefix(it, prefix, false); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			if hidden(prefix, item.Key()) {
+				continue
+			}
+			val, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			one := T(new(V))
+			if _, err := one.Unmarshal(item.KeyCopy(nil), val); err != nil {
+				return err
+			}
+			if filter != nil && !filter(one) {
+				continue
+			}
+			obj, err := json.Marshal(one)
+			if err != nil {
+				return err
+			}
+			if err := enc.Encode(ExportRecord{Key: item.KeyCopy(nil), KeyText: printable(item.Key()), Object: obj, Value: val}); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// write each key & stored value under prefix as one JSON line, without knowing their type.
+// a whole db export (nil or empty prefix) includes helper's own records, i.e. index entries, id sequences
+// and schema version, so ImportRaw restores them as well. a prefix export has no index entries,
+// import its records of Indexed types by ImportObjects to rebuild their indexes.
+func ExportRaw(db *badger.DB, w io.Writer, prefix []byte) (n int, err error) {
+	enc := json.NewEncoder(w)
+	err = db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(scanOptions(prefix, false))
+		defer it.Close()
+
+		for seekPrefix(it, prefix, false); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			if len(prefix) > 0 && hidden(prefix, item.Key()) {
+				continue
+			}
+			val, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			if err := enc.Encode(ExportRecord{
+				Key:       item.KeyCopy(nil),
+				KeyText:   printable(item.Key()),
+				Value:     val,
+				ExpiresAt: item.ExpiresAt(),
+				UserMeta:  item.UserMeta(),
+			}); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// -------------------------------------------------------------------- //
+
+// read JSON Lines records one by one, line is the 1-based record number
+func readRecords(r io.Reader, fn func(line int, rec *ExportRecord) error) error {
+	dec := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		rec := &ExportRecord{}
+		if err := dec.Decode(rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("record %d: %w", line, err)
+		}
+		if len(rec.Key) == 0 {
+			return fmt.Errorf("record %d: key CANNOT be empty", line)
+		}
+		if err := fn(line, rec); err != nil {
+			return fmt.Errorf("record %d: %w", line, err)
+		}
+	}
+}
+
+// rebuild objects from JSON Lines of ExportObjects or ExportRaw by Unmarshal of stored value,
+// or by encoding/json of object for a record without value (e.g. written by hand),
+// then store them by UpsertObjects in batches, so indexes are rebuilt as well.
+// n counts records of fully stored batches, a failing batch may be stored in part beyond n.
+func ImportObjects[V any, T PtrDbAccessible[V]](r io.Reader, batch int) (n int, err error) {
+	if batch <= 0 {
+		batch = DefaultImportBatch
+	}
+	objects := make([]T, 0, batch)
+	flush := func() error {
+		if len(objects) == 0 {
+			return nil
+		}
+		if err := UpsertObjects[V, T](objects...); err != nil {
+			return err
+		}
+		n += len(objects)
+		objects = objects[:0]
+		return nil
+	}
+
+	err = readRecords(r, func(line int, rec *ExportRecord) error {
+		one := T(new(V))
+		switch {
+		case len(rec.Value) > 0:
+			if _, err := one.Unmarshal(rec.Key, rec.Value); err != nil {
+				return err
+			}
+		case len(rec.Object) > 0:
+			if err := json.Unmarshal(rec.Object, one); err != nil {
+				return err
+			}
+			if !bytes.Equal(one.Key(), rec.Key) {
+				return fmt.Errorf("object key '%s' MUST be record key '%s'", printable(one.Key()), printable(rec.Key))
+			}
+		default:
+			return errors.New("object or value CANNOT be empty")
+		}
+		objects = append(objects, one)
+		if len(objects) == batch {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, flush()
+}
+
+// store JSON Lines records of ExportRaw into db as they are, with their TTL and user meta.
+// values are stored without index maintenance: records of a whole db export carry their index entries,
+// records of Indexed types from a prefix export should be imported by ImportObjects instead.
+// typed records are refused, import them by ImportObjects to maintain indexes.
+// records are committed in transactions of up to DefaultImportBatch, n is the count of committed records, also on error.
+func ImportRaw(db *badger.DB, r io.Reader) (n int, err error) {
+	entries := make([]*badger.Entry, 0, DefaultImportBatch)
+	flush := func() error {
+		// txn keeps entry, give it a fresh one as a retried chunk reuses entries
+		committed, err := updateEach(db, entries, func(txn *badger.Txn, e *badger.Entry) error {
+			return txn.SetEntry(cloneEntry(e))
+		})
+		n += committed
+		entries = entries[:0]
+		return err
+	}
+
+	err = readRecords(r, func(line int, rec *ExportRecord) error {
+		if len(rec.Object) > 0 {
+			return errors.New("typed record MUST be imported by ImportObjects")
+		}
+		e := badger.NewEntry(rec.Key, rec.Value).WithMeta(rec.UserMeta)
+		e.ExpiresAt = rec.ExpiresAt
+		entries = append(entries, e)
+		if len(entries) == DefaultImportBatch {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, flush()
+}
+
+func cloneEntry(e *badger.Entry) *badger.Entry {
+	c := badger.NewEntry(e.Key, e.Value).WithMeta(e.UserMeta)
+	c.ExpiresAt = e.ExpiresAt
+	return c
+}
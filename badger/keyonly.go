@@ -0,0 +1,98 @@
+package badgerhelper
+
+import (
+	"errors"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// key-only helpers never fetch values nor call Unmarshal, so they only read the LSM tree
+
+func keyOnlyOptions(prefix []byte) badger.IteratorOptions {
+	opts := scanOptions(prefix, false)
+	opts.PrefetchValues = false
+	return opts
+}
+
+func listKeys(txn *badger.Txn, prefix []byte) [][]byte {
+	it := txn.NewIterator(keyOnlyOptions(prefix))
+	defer it.Close()
+
+	rt := [][]byte{}
+	for seekPrefix(it, prefix, false); it.ValidForPrefix(prefix); it.Next() {
+		if hidden(prefix, it.Item().Key()) {
+			continue
+		}
+		rt = append(rt, it.Item().KeyCopy(nil))
+	}
+	return rt
+}
+
+func countKeys(txn *badger.Txn, prefix []byte) int {
+	it := txn.NewIterator(keyOnlyOptions(prefix))
+	defer it.Close()
+
+	n := 0
+	for seekPrefix(it, prefix, false); it.ValidForPrefix(prefix); it.Next() {
+		if hidden(prefix, it.Item().Key()) {
+			continue
+		}
+		n++
+	}
+	return n
+}
+
+func exists(txn *badger.Txn, key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, nil
+	}
+	_, err := txn.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// all keys under prefix, all keys if prefix is nil or empty
+func ListKeys[V any, T PtrDbAccessible[V]](prefix []byte) (rt [][]byte, err error) {
+	err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
+		rt = listKeys(txn, prefix)
+		return nil
+	})
+	return rt, err
+}
+
+// count of keys under prefix, all keys if prefix is nil or empty
+func CountKeys[V any, T PtrDbAccessible[V]](prefix []byte) (n int, err error) {
+	err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
+		n = countKeys(txn, prefix)
+		return nil
+	})
+	return n, err
+}
+
+func Exists[V any, T PtrDbAccessible[V]](key []byte) (found bool, err error) {
+	err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
+		found, err = exists(txn, key)
+		return err
+	})
+	return found, err
+}
+
+// existence of each key in one read transaction, in the same order of keys
+func ExistsMany[V any, T PtrDbAccessible[V]](keys ...[]byte) ([]bool, error) {
+	var (
+		rt  = make([]bool, len(keys))
+		err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
+			for i, key := range keys {
+				found, err := exists(txn, key)
+				if err != nil {
+					return err
+				}
+				rt[i] = found
+			}
+			return nil
+		})
+	)
+	return rt, err
+}
@@ -0,0 +1,183 @@
+package keys
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// order-preserving tuple encoding for badger keys.
+// encoded keys sort by segments in order, so they work with prefix & range scans.
+// each segment starts with its kind byte. string is 0x00-escaped (0x00 => 0x00 0xFF) and 0x00 terminated,
+// int64 is big-endian with sign bit flipped, uint64 is big-endian, time is int64 unix nanoseconds (UTC).
+
+type Kind byte
+
+const (
+	KindString Kind = 0x01
+	KindInt    Kind = 0x02
+	KindUint   Kind = 0x03
+	KindTime   Kind = 0x04
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindString:
+		return "string"
+	case KindInt:
+		return "int"
+	case KindUint:
+		return "uint"
+	case KindTime:
+		return "time"
+	default:
+		return fmt.Sprintf("kind(0x%02x)", byte(k))
+	}
+}
+
+// one typed element of a composite key
+type Segment struct {
+	Kind Kind
+	Str  string
+	Int  int64
+	Uint uint64
+	Time time.Time
+}
+
+func Str(s string) Segment {
+	return Segment{Kind: KindString, Str: s}
+}
+
+func Int(i int64) Segment {
+	return Segment{Kind: KindInt, Int: i}
+}
+
+func Uint(u uint64) Segment {
+	return Segment{Kind: KindUint, Uint: u}
+}
+
+// time is stored as unix nanoseconds, valid between year 1678 and 2262
+func Time(t time.Time) Segment {
+	return Segment{Kind: KindTime, Time: t.UTC()}
+}
+
+// segment value as string, int64, uint64 or time.Time
+func (s Segment) Value() any {
+	switch s.Kind {
+	case KindString:
+		return s.Str
+	case KindInt:
+		return s.Int
+	case KindUint:
+		return s.Uint
+	case KindTime:
+		return s.Time
+	default:
+		return nil
+	}
+}
+
+func (s Segment) String() string {
+	return fmt.Sprint(s.Value())
+}
+
+// -------------------------------------------------------------------- //
+
+func appendString(buf []byte, s string, terminate bool) []byte {
+	for i := 0; i < len(s); i++ {
+		if buf = append(buf, s[i]); s[i] == 0x00 {
+			buf = append(buf, 0xFF)
+		}
+	}
+	if terminate {
+		buf = append(buf, 0x00)
+	}
+	return buf
+}
+
+func appendSegment(buf []byte, seg Segment, terminate bool) []byte {
+	buf = append(buf, byte(seg.Kind))
+	switch seg.Kind {
+	case KindString:
+		return appendString(buf, seg.Str, terminate)
+	case KindInt:
+		return binary.BigEndian.AppendUint64(buf, uint64(seg.Int)^(1<<63))
+	case KindUint:
+		return binary.BigEndian.AppendUint64(buf, seg.Uint)
+	case KindTime:
+		return binary.BigEndian.AppendUint64(buf, uint64(seg.Time.UnixNano())^(1<<63))
+	default:
+		panic(fmt.Sprintf("invalid key segment %v", seg.Kind))
+	}
+}
+
+// composite key of segments
+func Encode(segs ...Segment) []byte {
+	buf := []byte{}
+	for _, seg := range segs {
+		buf = appendSegment(buf, seg, true)
+	}
+	return buf
+}
+
+// prefix matching all keys starting with these complete segments, same as Encode
+func Prefix(segs ...Segment) []byte {
+	return Encode(segs...)
+}
+
+// prefix like Prefix, but the last string segment is left open,
+// e.g. PrefixOpen(Str("user"), Str("A")) matches user keys whose 2nd segment starts with "A"
+func PrefixOpen(segs ...Segment) []byte {
+	buf := []byte{}
+	for i, seg := range segs {
+		buf = appendSegment(buf, seg, i < len(segs)-1)
+	}
+	return buf
+}
+
+// segments back from a key made by Encode
+func Decode(key []byte) ([]Segment, error) {
+	segs := []Segment{}
+	for len(key) > 0 {
+		kind := Kind(key[0])
+		key = key[1:]
+		switch kind {
+		case KindString:
+			buf := []byte{}
+			for {
+				i := bytes.IndexByte(key, 0x00)
+				if i < 0 {
+					return nil, errors.New("unterminated string segment")
+				}
+				buf = append(buf, key[:i]...)
+				if i+1 < len(key) && key[i+1] == 0xFF {
+					buf = append(buf, 0x00)
+					key = key[i+2:]
+					continue
+				}
+				key = key[i+1:]
+				break
+			}
+			segs = append(segs, Str(string(buf)))
+		case KindInt, KindUint, KindTime:
+			if len(key) < 8 {
+				return nil, fmt.Errorf("truncated %v segment", kind)
+			}
+			u := binary.BigEndian.Uint64(key)
+			key = key[8:]
+			switch kind {
+			case KindInt:
+				segs = append(segs, Int(int64(u^(1<<63))))
+			case KindUint:
+				segs = append(segs, Uint(u))
+			case KindTime:
+				segs = append(segs, Time(time.Unix(0, int64(u^(1<<63)))))
+			}
+		default:
+			return nil, fmt.Errorf("invalid key segment %v", kind)
+		}
+	}
+	return segs, nil
+}
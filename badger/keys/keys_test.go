@@ -0,0 +1,130 @@
+package keys
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+func segEqual(a, b Segment) bool {
+	if a.Kind != b.Kind {
+		return false
+	}
+	if a.Kind == KindTime {
+		return a.Time.Equal(b.Time)
+	}
+	return a.Value() == b.Value()
+}
+
+func TestEncodeOrder(t *testing.T) {
+	ts := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	// each group is listed in ascending order
+	groups := map[string][][]Segment{
+		"int": {
+			{Int(math.MinInt64)}, {Int(-300)}, {Int(-5)}, {Int(-1)}, {Int(0)}, {Int(1)}, {Int(3)}, {Int(300)}, {Int(math.MaxInt64)},
+		},
+		"uint": {
+			{Uint(0)}, {Uint(1)}, {Uint(255)}, {Uint(256)}, {Uint(math.MaxUint64)},
+		},
+		"time": {
+			{Time(time.Unix(0, 0).Add(-time.Hour))}, {Time(time.Unix(0, 0))}, {Time(ts)}, {Time(ts.Add(time.Nanosecond))},
+		},
+		"string": {
+			{Str("")}, {Str("\x00")}, {Str("a")}, {Str("a\x00")}, {Str("a\x00b")}, {Str("a\x01")}, {Str("a b")}, {Str("ab")}, {Str("b")},
+		},
+		// segments of different kinds sort by kind
+		"tuple": {
+			{Str("user")},
+			{Str("user"), Str("a")},
+			{Str("user"), Int(-5)},
+			{Str("user"), Int(3)},
+			{Str("user"), Int(300)},
+			{Str("user\x00a"), Time(ts)},
+			{Str("user^x"), Uint(1)},
+			{Str("users")},
+		},
+	}
+	for name, group := range groups {
+		for i := 1; i < len(group); i++ {
+			prev, cur := Encode(group[i-1]...), Encode(group[i]...)
+			if bytes.Compare(prev, cur) >= 0 {
+				t.Errorf("%s: %v MUST sort before %v, got %x >= %x", name, group[i-1], group[i], prev, cur)
+			}
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	ts := time.Date(2024, 7, 1, 12, 30, 0, 123, time.FixedZone("X", 3600))
+
+	tests := [][]Segment{
+		{},
+		{Str("")},
+		{Str("user")},
+		{Str("a\x00b\x00"), Str("\x00\xff")},
+		{Int(math.MinInt64), Int(-1), Int(0), Int(math.MaxInt64)},
+		{Uint(0), Uint(math.MaxUint64)},
+		{Time(ts)},
+		{Str("user"), Int(-5), Uint(7), Time(ts), Str("")},
+	}
+	for _, segs := range tests {
+		got, err := Decode(Encode(segs...))
+		if err != nil {
+			t.Errorf("%v: %v", segs, err)
+			continue
+		}
+		if len(got) != len(segs) {
+			t.Errorf("%v: decoded %v", segs, got)
+			continue
+		}
+		for i := range segs {
+			if !segEqual(got[i], segs[i]) {
+				t.Errorf("%v: segment %d decoded as %v", segs, i, got[i])
+			}
+		}
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix []byte
+		key    []byte
+		match  bool
+	}{
+		{"complete segment", Prefix(Str("user")), Encode(Str("user"), Int(1)), true},
+		{"complete segment, same key", Prefix(Str("user")), Encode(Str("user")), true},
+		{"complete segment, longer string", Prefix(Str("user")), Encode(Str("users")), false},
+		{"open segment", PrefixOpen(Str("user"), Str("A")), Encode(Str("user"), Str("Alice")), true},
+		{"open segment, same string", PrefixOpen(Str("user"), Str("A")), Encode(Str("user"), Str("A"), Int(1)), true},
+		{"open segment, other string", PrefixOpen(Str("user"), Str("A")), Encode(Str("user"), Str("Bob")), false},
+		{"open segment, other first segment", PrefixOpen(Str("user"), Str("A")), Encode(Str("userX"), Str("A")), false},
+		{"open segment, escaped 0x00", PrefixOpen(Str("a\x00")), Encode(Str("a\x00b")), true},
+		{"open segment, not escaped 0x00", PrefixOpen(Str("a\x00")), Encode(Str("a"), Str("b")), false},
+		{"open non-string segment", PrefixOpen(Str("user"), Int(1)), Encode(Str("user"), Int(1), Str("x")), true},
+	}
+	for _, tt := range tests {
+		if got := bytes.HasPrefix(tt.key, tt.prefix); got != tt.match {
+			t.Errorf("%s: match %v, want %v", tt.name, got, tt.match)
+		}
+	}
+
+	if !bytes.Equal(PrefixOpen(Str("user"), Int(1)), Encode(Str("user"), Int(1))) {
+		t.Error("PrefixOpen with last non-string segment MUST be same as Encode")
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	tests := map[string][]byte{
+		"unterminated string": {byte(KindString), 'a'},
+		"truncated int":       {byte(KindInt), 0, 1},
+		"invalid kind":        {0x09, 0},
+	}
+	for name, key := range tests {
+		if _, err := Decode(key); err == nil {
+			t.Errorf("%s: error expected", name)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package badgerhelper
+
+import (
+	"bytes"
+)
+
+// reserved key space for helper's own records, e.g. secondary index entries.
+// scans skip it unless the scan prefix itself is inside it.
+var metaPrefix = []byte("\x00!bh!")
+
+// key in reserved space, parts are joined by 0x00
+func metaKey(parts ...[]byte) []byte {
+	return append(bytes.Clone(metaPrefix), bytes.Join(parts, []byte{0x00})...)
+}
+
+// key is a helper's own record which should not be seen by a scan on prefix
+func hidden(prefix, key []byte) bool {
+	return bytes.HasPrefix(key, metaPrefix) && !bytes.HasPrefix(prefix, metaPrefix)
+}
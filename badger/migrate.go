@@ -0,0 +1,265 @@
+package badgerhelper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// applied schema version of a db, kept in reserved key space
+var schemaKey = metaKey([]byte("schema"))
+
+// one numbered schema change on stored keys & values. migrations work on raw bytes,
+// so index entries of Indexed types are not maintained. if index fields change, upsert objects again to index new values,
+// old entries are left stale and skipped by GetObjectsByIndex.
+type Migration struct {
+	Version int    // > 0, unique, migrations run in ascending Version
+	Name    string // for report
+	Prefix  []byte // keys to rewrite, all keys if nil or empty
+	// new key & value of a stored one. nil newKey deletes key, same key & value leaves it unchanged.
+	// a new key is written besides, old key is deleted.
+	Rewrite func(key, val []byte) (newKey, newVal []byte, err error)
+}
+
+type MigrationResult struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	Scanned int    `json:"scanned"`
+	Changed int    `json:"changed"` // rewritten values or keys
+	Deleted int    `json:"deleted"`
+}
+
+type MigrationReport struct {
+	From    int               `json:"from"`
+	To      int               `json:"to"` // From in dry run
+	DryRun  bool              `json:"dry_run"`
+	Results []MigrationResult `json:"results"`
+}
+
+// applied schema version of db, 0 if no migration has run
+func SchemaVersion(db *badger.DB) (ver int, err error) {
+	err = db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(schemaKey)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			if len(val) != 8 {
+				return fmt.Errorf("invalid schema version record of %d bytes", len(val))
+			}
+			ver = int(binary.BigEndian.Uint64(val))
+			return nil
+		})
+	})
+	return ver, err
+}
+
+func setSchemaVersion(db *badger.DB, ver int) error {
+	return db.Update(func(txn *badger.Txn) error {
+		return txn.Set(schemaKey, binary.BigEndian.AppendUint64(nil, uint64(ver)))
+	})
+}
+
+// migrations sorted by Version, error if any is invalid
+func sortMigrations(migrations []Migration) ([]Migration, error) {
+	sorted := slices.Clone(migrations)
+	slices.SortFunc(sorted, func(a, b Migration) int {
+		return a.Version - b.Version
+	})
+	for i, m := range sorted {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("migration '%s': version MUST be greater than 0", m.Name)
+		}
+		if m.Rewrite == nil {
+			return nil, fmt.Errorf("migration %d '%s': Rewrite CANNOT be nil", m.Version, m.Name)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("migration version %d is duplicated", m.Version)
+		}
+	}
+	return sorted, nil
+}
+
+// run migrations newer than schema version of db in ascending order, schema version is updated after each one.
+// keys are read from a snapshot and written by badger WriteBatch, so db should not be written by others meanwhile.
+// dryRun writes nothing but counts keys which would change. each dry run migration sees changes of former ones,
+// which are kept in memory, so its counts match a real run.
+// rewritten keys keep their TTL & user meta.
+// a migration failing halfway may leave part of its keys rewritten, so Rewrite should accept already rewritten input.
+func Migrate(db *badger.DB, migrations []Migration, dryRun bool) (*MigrationReport, error) {
+	sorted, err := sortMigrations(migrations)
+	if err != nil {
+		return nil, err
+	}
+	from, err := SchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var dry map[string]*dryEntry
+	if dryRun {
+		dry = map[string]*dryEntry{}
+	}
+	report := &MigrationReport{From: from, To: from, DryRun: dryRun, Results: []MigrationResult{}}
+	for _, m := range sorted {
+		if m.Version <= from {
+			continue
+		}
+		result, err := runMigration(db, m, dry)
+		report.Results = append(report.Results, result)
+		if err != nil {
+			return report, fmt.Errorf("migration %d '%s': %w", m.Version, m.Name, err)
+		}
+		if dryRun {
+			continue
+		}
+		if err := setSchemaVersion(db, m.Version); err != nil {
+			return report, err
+		}
+		report.To = m.Version
+	}
+	return report, nil
+}
+
+// key state left by former migrations of a dry run
+type dryEntry struct {
+	val       []byte
+	expiresAt uint64
+	userMeta  byte
+	deleted   bool
+}
+
+// run m on a snapshot of db, writing by badger WriteBatch.
+// dry run (dry not nil) reads keys changed by former migrations from dry, and puts its own changes into dry.
+func runMigration(db *badger.DB, m Migration, dry map[string]*dryEntry) (MigrationResult, error) {
+	result := MigrationResult{Version: m.Version, Name: m.Name}
+	dryRun := dry != nil
+	staged := map[string]*dryEntry{}
+
+	wb := db.NewWriteBatch()
+	defer wb.Cancel()
+
+	apply := func(key, val []byte, expiresAt uint64, userMeta byte) error {
+		result.Scanned++
+		newKey, newVal, err := m.Rewrite(key, val)
+		if err != nil {
+			return fmt.Errorf("key '%s': %w", printable(key), err)
+		}
+		switch {
+		case newKey == nil:
+			result.Deleted++
+			if dryRun {
+				staged[string(key)] = &dryEntry{deleted: true}
+				return nil
+			}
+			return wb.Delete(key)
+		case bytes.Equal(newKey, key) && bytes.Equal(newVal, val):
+			return nil
+		}
+
+		result.Changed++
+		if dryRun {
+			if !bytes.Equal(newKey, key) {
+				staged[string(key)] = &dryEntry{deleted: true}
+			}
+			staged[string(newKey)] = &dryEntry{val: newVal, expiresAt: expiresAt, userMeta: userMeta}
+			return nil
+		}
+		if !bytes.Equal(newKey, key) {
+			if err := wb.Delete(key); err != nil {
+				return err
+			}
+		}
+		e := badger.NewEntry(newKey, newVal).WithMeta(userMeta)
+		e.ExpiresAt = expiresAt
+		return wb.SetEntry(e)
+	}
+
+	err := db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(scanOptions(m.Prefix, false))
+		defer it.Close()
+
+		for seekPrefix(it, m.Prefix, false); it.ValidForPrefix(m.Prefix); it.Next() {
+			item := it.Item()
+			if hidden(m.Prefix, item.Key()) {
+				continue
+			}
+			if _, ok := dry[string(item.Key())]; ok {
+				continue // changed by a former dry run migration, see below
+			}
+			val, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			if err := apply(item.KeyCopy(nil), val, item.ExpiresAt(), item.UserMeta()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	if !dryRun {
+		return result, wb.Flush()
+	}
+
+	keys := slices.Sorted(maps.Keys(dry))
+	for _, key := range keys {
+		d := dry[key]
+		if d.deleted || !bytes.HasPrefix([]byte(key), m.Prefix) || hidden(m.Prefix, []byte(key)) {
+			continue
+		}
+		if err := apply([]byte(key), d.val, d.expiresAt, d.userMeta); err != nil {
+			return result, err
+		}
+	}
+	maps.Copy(dry, staged)
+	return result, nil
+}
+
+// -------------------------------------------------------------------- //
+
+var migrations = map[string][]Migration{}
+
+// register migrations of db name, Open runs the pending ones right after opening it (except read-only one)
+func RegisterMigrations(name string, ms ...Migration) error {
+	mtxReg.Lock()
+	defer mtxReg.Unlock()
+
+	all := append(slices.Clone(migrations[name]), ms...)
+	if _, err := sortMigrations(all); err != nil {
+		return err
+	}
+	migrations[name] = all
+	return nil
+}
+
+// forget migrations registered for db name, e.g. for tests registering their own ones
+func UnregisterMigrations(name string) {
+	mtxReg.Lock()
+	defer mtxReg.Unlock()
+
+	delete(migrations, name)
+}
+
+// called by Open without registry locked, migrations of name are taken under lock
+func migrateRegistered(name string, db *badger.DB) error {
+	mtxReg.Lock()
+	ms := slices.Clone(migrations[name])
+	mtxReg.Unlock()
+
+	if len(ms) == 0 || db.Opts().ReadOnly {
+		return nil
+	}
+	_, err := Migrate(db, ms, false)
+	return err
+}
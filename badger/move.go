@@ -0,0 +1,98 @@
+package badgerhelper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// store object and delete the one at oldKey in one transaction, object key may differ from oldKey.
+// replaced is the object which was at oldKey, nil if there was none (object is still stored).
+func ReplaceObject[V any, T PtrDbAccessible[V]](oldKey []byte, object T) (replaced T, err error) {
+	if len(oldKey) == 0 {
+		return nil, errors.New("oldKey CANNOT be empty")
+	}
+	if len(object.Key()) == 0 {
+		return nil, errors.New("object.Key CANNOT be empty")
+	}
+	err = object.BadgerDB().Update(func(txn *badger.Txn) error {
+		if replaced, err = getOneObject[V, T](txn, oldKey); err != nil {
+			return err
+		}
+		if replaced != nil && !bytes.Equal(oldKey, object.Key()) {
+			if _, err = deleteOneObject[V, T](txn, oldKey, nil); err != nil {
+				return err
+			}
+		}
+		return upsertOneObject[V, T](txn, object)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return replaced, nil
+}
+
+// optional for DbAccessible type whose key is kept in its value (not derived from db key),
+// MoveObject calls SetKey to point object to the new key before marshaling it again
+type KeySetter interface {
+	SetKey(key []byte)
+}
+
+// move stored object from oldKey to newKey in one transaction, keeping its TTL & user meta.
+// object is decoded and marshaled again at newKey: a KeySetter gets newKey by SetKey,
+// other types are decoded with newKey as db key. either way moved object Key must be newKey.
+// moved is the object now at newKey, nil if oldKey does not exist (nothing is done).
+// replaced is the object which was at newKey before moving, nil if there was none.
+func MoveObject[V any, T PtrDbAccessible[V]](oldKey, newKey []byte) (moved, replaced T, err error) {
+	if len(oldKey) == 0 || len(newKey) == 0 {
+		return nil, nil, errors.New("oldKey & newKey CANNOT be empty")
+	}
+	err = T(new(V)).BadgerDB().Update(func(txn *badger.Txn) error {
+		item, err := txn.Get(oldKey)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		val, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		one := T(new(V))
+		if setter, ok := any(one).(KeySetter); ok {
+			if _, err = one.Unmarshal(oldKey, val); err != nil {
+				return err
+			}
+			setter.SetKey(newKey)
+		} else if _, err = one.Unmarshal(newKey, val); err != nil {
+			return err
+		}
+		if !bytes.Equal(one.Key(), newKey) {
+			return fmt.Errorf("moved object key '%s' MUST be newKey '%s', implement KeySetter", one.Key(), newKey)
+		}
+		if bytes.Equal(oldKey, newKey) {
+			moved = one
+			return nil
+		}
+
+		if replaced, err = getOneObject[V, T](txn, newKey); err != nil {
+			return err
+		}
+		opts := []EntryOption{withExpiresAt(item.ExpiresAt()), WithUserMeta(item.UserMeta())}
+		if _, err = deleteOneObject[V, T](txn, oldKey, nil); err != nil {
+			return err
+		}
+		if err = upsertOneObject[V, T](txn, one, opts...); err != nil {
+			return err
+		}
+		moved = one
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return moved, replaced, nil
+}
@@ -0,0 +1,75 @@
+package badgerhelper
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// one page of objects under prefix, starting right after cursor (last returned key).
+// cursor is nil or empty for the first page. returned next cursor is nil when no more objects,
+// otherwise pass it back to fetch the following page.
+func GetObjectsPage[V any, T PtrDbAccessible[V]](prefix []byte, filter func(T) bool, size int, cursor []byte) (page []T, next []byte, err error) {
+	return getObjectsPage[V, T](prefix, filter, size, cursor, false)
+}
+
+// same as GetObjectsPage, but pages go in descending key order, e.g. newest-first on time-ordered keys.
+func GetObjectsPageDesc[V any, T PtrDbAccessible[V]](prefix []byte, filter func(T) bool, size int, cursor []byte) (page []T, next []byte, err error) {
+	return getObjectsPage[V, T](prefix, filter, size, cursor, true)
+}
+
+func getObjectsPage[V any, T PtrDbAccessible[V]](prefix []byte, filter func(T) bool, size int, cursor []byte, reverse bool) (page []T, next []byte, err error) {
+
+	if size <= 0 {
+		return nil, nil, errors.New("page size MUST be positive")
+	}
+	if len(cursor) > 0 && !bytes.HasPrefix(cursor, prefix) {
+		return nil, nil, errors.New("cursor MUST start with input prefix")
+	}
+
+	page = []T{}
+	last := []byte{}
+	err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(scanOptions(prefix, reverse))
+		defer it.Close()
+
+		// Seek on cursor key in both directions, then skip cursor itself
+		if len(cursor) > 0 {
+			it.Seek(cursor)
+		} else {
+			seekPrefix(it, prefix, reverse)
+		}
+		for ; it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			if hidden(prefix, item.Key()) {
+				continue
+			}
+			if len(cursor) > 0 && bytes.Equal(cursor, item.Key()) {
+				continue
+			}
+			one := T(new(V))
+			if err := item.Value(func(val []byte) error {
+				_, err := one.Unmarshal(item.Key(), val)
+				return err
+			}); err != nil {
+				return err
+			}
+			if filter != nil && !filter(one) {
+				continue
+			}
+			if len(page) == size {
+				// another matching object remains, resume from the last returned one
+				next = last
+				break
+			}
+			page = append(page, one)
+			last = item.KeyCopy(last)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return page, next, nil
+}
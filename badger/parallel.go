@@ -0,0 +1,126 @@
+package badgerhelper
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/dgraph-io/badger/v4/pb"
+	"github.com/dgraph-io/ristretto/z"
+)
+
+type ParallelOptions struct {
+	NumGo   int  // goroutines iterating & decoding key ranges, badger default (8) if 0
+	Ordered bool // deliver objects in ascending key order, they are buffered until scan ends
+}
+
+type keyedObject[T any] struct {
+	key    []byte
+	object T
+}
+
+// scan prefix concurrently with badger Stream framework, all objects if prefix is nil or empty.
+// values are decoded into T by each worker, fn is called one at a time (never concurrently).
+// in unordered mode objects are delivered as soon as decoded, in no particular order.
+// scan stops at the first error from Unmarshal or fn, which is returned.
+func ScanParallel[V any, T PtrDbAccessible[V]](ctx context.Context, prefix []byte, filter func(T) bool, opt ParallelOptions, fn func(T) error) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var (
+		mtx     sync.Mutex
+		ordered []keyedObject[T]
+	)
+
+	stream := T(new(V)).BadgerDB().NewStream()
+	stream.Prefix = prefix
+	if opt.NumGo > 0 {
+		stream.NumGo = opt.NumGo
+	}
+	stream.LogPrefix = "badgerhelper.ScanParallel"
+	stream.ChooseKey = func(item *badger.Item) bool {
+		return !hidden(prefix, item.Key()) && !item.IsDeletedOrExpired()
+	}
+	// objects are consumed here by workers, nothing is passed on to Send
+	stream.KeyToList = func(key []byte, itr *badger.Iterator) (*pb.KVList, error) {
+		one := T(new(V))
+		if err := itr.Item().Value(func(val []byte) error {
+			_, err := one.Unmarshal(key, val)
+			return err
+		}); err != nil {
+			cancel(err)
+			return nil, err
+		}
+		if filter != nil && !filter(one) {
+			return nil, nil
+		}
+
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		if opt.Ordered {
+			ordered = append(ordered, keyedObject[T]{key: key, object: one})
+			return nil, nil
+		}
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+		if err := fn(one); err != nil {
+			cancel(err)
+			return nil, err
+		}
+		return nil, nil
+	}
+	stream.Send = func(buf *z.Buffer) error {
+		return nil
+	}
+
+	if err := stream.Orchestrate(ctx); err != nil {
+		if cause := context.Cause(ctx); cause != nil {
+			return cause
+		}
+		return err
+	}
+	// KeyToList errors are only logged by badger, take them back from ctx
+	if err := context.Cause(ctx); err != nil {
+		return err
+	}
+
+	if opt.Ordered {
+		slices.SortFunc(ordered, func(a, b keyedObject[T]) int {
+			return bytes.Compare(a.key, b.key)
+		})
+		for _, ko := range ordered {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(ko.object); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// channel version of ScanParallel. object channel is closed when scan ends,
+// then error channel gets the scan error (nil if succeeded) and is closed.
+// caller should drain object channel or cancel ctx.
+func StreamObjects[V any, T PtrDbAccessible[V]](ctx context.Context, prefix []byte, filter func(T) bool, opt ParallelOptions) (<-chan T, <-chan error) {
+	cObj, cErr := make(chan T), make(chan error, 1)
+	go func() {
+		defer close(cErr)
+		err := ScanParallel[V, T](ctx, prefix, filter, opt, func(one T) error {
+			select {
+			case cObj <- one:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		close(cObj)
+		cErr <- err
+	}()
+	return cObj, cErr
+}
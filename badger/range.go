@@ -0,0 +1,148 @@
+package badgerhelper
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// walk items with key in [start, end), stop after 'limit' items are taken by proc.
+// empty end means no upper bound, limit <= 0 means no limit.
+func scanRange(txn *badger.Txn, start, end []byte, limit int, proc func(item *badger.Item) (taken bool, err error)) error {
+	if len(end) > 0 && bytes.Compare(start, end) >= 0 {
+		return nil
+	}
+
+	opts := badger.DefaultIteratorOptions
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	n := 0
+	for it.Seek(start); it.Valid(); it.Next() {
+		item := it.Item()
+		if len(end) > 0 && bytes.Compare(item.Key(), end) >= 0 {
+			break
+		}
+		if hidden(start, item.Key()) {
+			continue
+		}
+		taken, err := proc(item)
+		if err != nil {
+			return err
+		}
+		if taken {
+			if n++; limit > 0 && n == limit {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func checkRange(start, end []byte) error {
+	if len(end) > 0 && bytes.Compare(start, end) > 0 {
+		return errors.New("range start MUST NOT be greater than end")
+	}
+	return nil
+}
+
+// objects with key in [start, end). empty end means no upper bound, limit <= 0 means no limit.
+func GetObjectsInRange[V any, T PtrDbAccessible[V]](start, end []byte, filter func(T) bool, limit int) ([]T, error) {
+	if err := checkRange(start, end); err != nil {
+		return nil, err
+	}
+	var (
+		rt  = []T{}
+		err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
+			return scanRange(txn, start, end, limit, func(item *badger.Item) (taken bool, err error) {
+				err = item.Value(func(val []byte) error {
+					one := T(new(V))
+					if _, err := one.Unmarshal(item.Key(), val); err != nil {
+						return err
+					}
+					if filter == nil || filter(one) {
+						rt = append(rt, one)
+						taken = true
+					}
+					return nil
+				})
+				return taken, err
+			})
+		})
+	)
+	return rt, err
+}
+
+// use Unmarshal returned data as map-value for key in [start, end), filter key is []byte type
+func GetMapInRange[V any, T PtrDbAccessible[V]](start, end []byte, filter func([]byte, any) bool, limit int) (map[string]any, error) {
+	if err := checkRange(start, end); err != nil {
+		return nil, err
+	}
+	var (
+		rt  = make(map[string]any)
+		err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
+			return scanRange(txn, start, end, limit, func(item *badger.Item) (taken bool, err error) {
+				err = item.Value(func(val []byte) error {
+					key := item.Key()
+					data, err := T(new(V)).Unmarshal(key, val)
+					if err != nil {
+						return err
+					}
+					if filter == nil || filter(key, data) {
+						rt[string(key)] = data
+						taken = true
+					}
+					return nil
+				})
+				return taken, err
+			})
+		})
+	)
+	return rt, err
+}
+
+// count of objects with key in [start, end), counting stops at limit if limit > 0
+func GetObjectCountInRange[V any, T PtrDbAccessible[V]](start, end []byte, filter func(T) bool, limit int) (int, error) {
+	if err := checkRange(start, end); err != nil {
+		return 0, err
+	}
+	var (
+		n   = 0
+		err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
+			return scanRange(txn, start, end, limit, func(item *badger.Item) (taken bool, err error) {
+				err = item.Value(func(val []byte) error {
+					one := T(new(V))
+					if _, err := one.Unmarshal(item.Key(), val); err != nil {
+						n = 0
+						return err
+					}
+					if filter == nil || filter(one) {
+						n++
+						taken = true
+					}
+					return nil
+				})
+				return taken, err
+			})
+		})
+	)
+	return n, err
+}
+
+// delete objects with key in [start, end), at most limit objects if limit > 0
+func DeleteObjectsInRange[V any, T PtrDbAccessible[V]](start, end []byte, limit int) (n int, err error) {
+	if err := checkRange(start, end); err != nil {
+		return 0, err
+	}
+	err = T(new(V)).BadgerDB().Update(func(txn *badger.Txn) error {
+		return scanRange(txn, start, end, limit, func(item *badger.Item) (bool, error) {
+			if err = deleteItem[V, T](txn, item); err != nil {
+				return false, err
+			}
+			n++
+			return true, nil
+		})
+	})
+	return n, err
+}
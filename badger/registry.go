@@ -0,0 +1,248 @@
+package badgerhelper
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+type DBOptions struct {
+	Dir            string                                  // db directory, in-memory db if empty
+	InMemory       bool                                    // keep nothing on disk, Dir is ignored
+	ReadOnly       bool                                    // open existing Dir without writing, e.g. for a second process
+	EncryptionKey  []byte                                  // AES key of 16, 24 or 32 bytes, no encryption if empty
+	IndexCacheSize int64                                   // bytes of block index cache, required by encryption, 64MB if 0 then
+	KeyRotation    time.Duration                           // lifetime of each data key under EncryptionKey, badger default (10 days) if 0
+	GC             GCOptions                               // background value log GC, never for read-only db
+	Logger         badger.Logger                           // badger log output, silent if nil
+	Tune           func(opt badger.Options) badger.Options // any other badger option, applied last
+}
+
+// index cache badger requires for encrypted db when IndexCacheSize is not given
+const defaultIndexCacheSize = 64 << 20
+
+func (o DBOptions) badgerOptions() badger.Options {
+	opt := badger.DefaultOptions(o.Dir)
+	if o.InMemory || o.Dir == "" {
+		opt = badger.DefaultOptions("").WithInMemory(true)
+	}
+	opt = opt.WithReadOnly(o.ReadOnly).WithLogger(o.Logger)
+	if o.IndexCacheSize > 0 {
+		opt = opt.WithIndexCacheSize(o.IndexCacheSize)
+	}
+	if len(o.EncryptionKey) > 0 {
+		opt = opt.WithEncryptionKey(o.EncryptionKey)
+		if o.IndexCacheSize == 0 {
+			opt = opt.WithIndexCacheSize(defaultIndexCacheSize)
+		}
+		if o.KeyRotation > 0 {
+			opt = opt.WithEncryptionKeyRotationDuration(o.KeyRotation)
+		}
+	}
+	if o.Tune != nil {
+		opt = o.Tune(opt)
+	}
+	return opt
+}
+
+type registered struct {
+	db   *badger.DB
+	opts DBOptions
+	gc   *GCRunner
+}
+
+var (
+	mtxReg   sync.Mutex
+	registry = map[string]*registered{}
+)
+
+// open a badger db and register it as name, which must not be open yet.
+// pending migrations registered for name are run before it is returned.
+func Open(name string, opts DBOptions) (*badger.DB, error) {
+	if len(name) == 0 {
+		return nil, errors.New("db name CANNOT be empty")
+	}
+	return openRegistered(name, opts)
+}
+
+// name is reserved while opening & migrating without registry lock held, so other dbs stay reachable.
+// DB gives nil for name until it is ready.
+func openRegistered(name string, opts DBOptions) (*badger.DB, error) {
+	mtxReg.Lock()
+	if _, ok := registry[name]; ok {
+		mtxReg.Unlock()
+		return nil, fmt.Errorf("db '%s' is already open", name)
+	}
+	registry[name] = &registered{opts: opts}
+	mtxReg.Unlock()
+
+	db, err := badger.Open(opts.badgerOptions())
+	if err != nil {
+		err = fmt.Errorf("open db '%s': %w", name, err)
+	} else if errMig := migrateRegistered(name, db); errMig != nil {
+		err = errors.Join(fmt.Errorf("migrate db '%s': %w", name, errMig), db.Close())
+	}
+
+	mtxReg.Lock()
+	defer mtxReg.Unlock()
+
+	if err != nil {
+		delete(registry, name)
+		return nil, err
+	}
+	gcOpts := opts.GC
+	if opts.ReadOnly {
+		gcOpts.Interval = 0
+	}
+	registry[name] = &registered{db: db, opts: opts, gc: StartGC(db, gcOpts)}
+	return db, nil
+}
+
+// registered db which is not being opened
+func openedRegistered(name string) (*registered, bool) {
+	r, ok := registry[name]
+	return r, ok && r.db != nil
+}
+
+// registered db of name, nil if it is not open (or still opening)
+func DB(name string) *badger.DB {
+	mtxReg.Lock()
+	defer mtxReg.Unlock()
+
+	if r, ok := openedRegistered(name); ok {
+		return r.db
+	}
+	return nil
+}
+
+// GC runner of registered db name, nil if it is not open. its Stats and RunOnce are for monitoring & manual GC.
+func GC(name string) *GCRunner {
+	mtxReg.Lock()
+	defer mtxReg.Unlock()
+
+	if r, ok := openedRegistered(name); ok {
+		return r.gc
+	}
+	return nil
+}
+
+// names of open dbs, sorted
+func DBNames() []string {
+	mtxReg.Lock()
+	defer mtxReg.Unlock()
+
+	names := make([]string, 0, len(registry))
+	for name, r := range registry {
+		if r.db != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func closeRegistered(name string, r *registered) error {
+	delete(registry, name)
+	r.gc.Stop()
+	return errors.Join(
+		ReleaseSequences(r.db),
+		r.db.Close(),
+	)
+}
+
+// release sequences of db name, close it and remove it from registry. nothing to do if it is not open.
+func Close(name string) error {
+	mtxReg.Lock()
+	defer mtxReg.Unlock()
+
+	r, ok := registry[name]
+	if !ok {
+		return nil
+	}
+	if r.db == nil {
+		return fmt.Errorf("db '%s' is being opened", name)
+	}
+	if err := closeRegistered(name, r); err != nil {
+		return fmt.Errorf("close db '%s': %w", name, err)
+	}
+	return nil
+}
+
+// close all registered dbs, every db is tried even if some fail. dbs being opened are left.
+func CloseAll() error {
+	mtxReg.Lock()
+	defer mtxReg.Unlock()
+
+	var errs []error
+	for name, r := range registry {
+		if r.db == nil {
+			continue
+		}
+		if err := closeRegistered(name, r); err != nil {
+			errs = append(errs, fmt.Errorf("close db '%s': %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// close db name and open it again with the same options, in-memory db comes back empty
+func Reopen(name string) (*badger.DB, error) {
+	mtxReg.Lock()
+	r, ok := openedRegistered(name)
+	if !ok {
+		mtxReg.Unlock()
+		return nil, fmt.Errorf("db '%s' is not open", name)
+	}
+	err := closeRegistered(name, r)
+	mtxReg.Unlock()
+
+	if err != nil {
+		return nil, fmt.Errorf("close db '%s': %w", name, err)
+	}
+	return openRegistered(name, r.opts)
+}
+
+// close all dbs when process gets one of sigs (SIGINT & SIGTERM if none given), then call after with
+// the signal and CloseAll error. if after is nil, process exits (1 if closing failed).
+// stop cancels the watching.
+func CloseOnSignal(after func(sig os.Signal, err error), sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, sigs...)
+
+	go func() {
+		select {
+		case sig := <-c:
+			signal.Stop(c)
+			err := CloseAll()
+			if after != nil {
+				after(sig, err)
+				return
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package badgerhelper
+
+import (
+	"bytes"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// iterator options for prefix scan.
+// Prefix option only applies to forward scan, reverse scan starts from the key just after prefix range.
+func scanOptions(prefix []byte, reverse bool) badger.IteratorOptions {
+	opts := badger.DefaultIteratorOptions
+	opts.Reverse = reverse
+	if !reverse {
+		opts.Prefix = prefix
+	}
+	return opts
+}
+
+// the smallest key which is greater than all keys having prefix.
+// nil if there is no such key, i.e. prefix is empty or all 0xFF.
+func prefixEnd(prefix []byte) []byte {
+	end := bytes.Clone(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xFF {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// move iterator to the first item of prefix range, in scan direction.
+// badger reverse Seek lands on the largest key <= input, so seek to prefix end and skip it if exists.
+func seekPrefix(it *badger.Iterator, prefix []byte, reverse bool) {
+	if !reverse {
+		it.Seek(prefix)
+		return
+	}
+	end := prefixEnd(prefix)
+	if end == nil {
+		it.Rewind()
+		return
+	}
+	if it.Seek(end); it.Valid() && bytes.Equal(end, it.Item().Key()) {
+		it.Next()
+	}
+}
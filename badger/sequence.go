@@ -0,0 +1,127 @@
+package badgerhelper
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// ids leased from db at a time by shared allocators, unused ids of a lease are given back on release
+var SequenceBandwidth uint64 = 100
+
+// objects which take an auto-increment id from InsertWithNewID, SetID must make Key() reflect id
+type IDSettable interface {
+	SetID(id uint64)
+}
+
+// named auto-increment id sequences of one db, ids start from 1.
+// sequences are stored in reserved key space, so they are not seen by scans.
+type IDAllocator struct {
+	mtx       sync.Mutex
+	db        *badger.DB
+	bandwidth uint64
+	seqs      map[string]*badger.Sequence
+}
+
+func NewIDAllocator(db *badger.DB, bandwidth uint64) *IDAllocator {
+	if bandwidth == 0 {
+		bandwidth = SequenceBandwidth
+	}
+	return &IDAllocator{
+		db:        db,
+		bandwidth: bandwidth,
+		seqs:      map[string]*badger.Sequence{},
+	}
+}
+
+// next id of sequence 'name', e.g. the key prefix which ids are for
+func (a *IDAllocator) Next(name string) (uint64, error) {
+	if len(name) == 0 {
+		return 0, errors.New("sequence name CANNOT be empty")
+	}
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	seq, ok := a.seqs[name]
+	if !ok {
+		var err error
+		if seq, err = a.db.GetSequence(metaKey([]byte("seq"), []byte(name)), a.bandwidth); err != nil {
+			return 0, err
+		}
+		a.seqs[name] = seq
+	}
+	id, err := seq.Next()
+	if err == nil && id == 0 {
+		// badger sequence starts from 0, keep 0 as 'no id'
+		id, err = seq.Next()
+	}
+	return id, err
+}
+
+// give leased but unused ids back to db, must be called before db is closed
+func (a *IDAllocator) Release() error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	var errs []error
+	for name, seq := range a.seqs {
+		errs = append(errs, seq.Release())
+		delete(a.seqs, name)
+	}
+	return errors.Join(errs...)
+}
+
+// -------------------------------------------------------------------- //
+
+var (
+	mtxAlloc   sync.Mutex
+	allocators = map[*badger.DB]*IDAllocator{}
+)
+
+// shared allocator of db, with SequenceBandwidth
+func Allocator(db *badger.DB) *IDAllocator {
+	mtxAlloc.Lock()
+	defer mtxAlloc.Unlock()
+
+	a, ok := allocators[db]
+	if !ok {
+		a = NewIDAllocator(db, SequenceBandwidth)
+		allocators[db] = a
+	}
+	return a
+}
+
+// release shared allocator of db, call it before closing db
+func ReleaseSequences(db *badger.DB) error {
+	mtxAlloc.Lock()
+	defer mtxAlloc.Unlock()
+
+	a, ok := allocators[db]
+	if !ok {
+		return nil
+	}
+	delete(allocators, db)
+	return a.Release()
+}
+
+// next id of sequence 'name' from shared allocator of T's db
+func NextID[V any, T PtrDbAccessible[V]](name string) (uint64, error) {
+	return Allocator(T(new(V)).BadgerDB()).Next(name)
+}
+
+// assign next id of sequence 'name' into object, then insert it before Marshal sees the id.
+// object must implement IDSettable, insert fails with ErrVersionConflict if its key is already taken.
+func InsertWithNewID[V any, T PtrDbAccessible[V]](object T, name string) (uint64, error) {
+	settable, ok := any(object).(IDSettable)
+	if !ok {
+		return 0, errors.New("object MUST implement IDSettable")
+	}
+	id, err := NextID[V, T](name)
+	if err != nil {
+		return 0, err
+	}
+	settable.SetID(id)
+	return id, InsertIfAbsent[V, T](object)
+}
@@ -0,0 +1,174 @@
+package badgerhelper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+type StatsOptions struct {
+	Prefix    []byte // only keys under it, all keys if nil or empty
+	Separator []byte // group keys by their part up to Depth-th Separator. if empty, by their first Depth bytes
+	Depth     int    // see Separator, all keys in one group if 0
+	Meta      bool   // report helper's own records (indexes, sequences, ...) as one more group
+}
+
+// storage of keys sharing Prefix. value sizes are stored sizes, values are not read.
+type PrefixStats struct {
+	Prefix     []byte `json:"-"`
+	Name       string `json:"prefix"` // printable Prefix
+	Keys       int    `json:"keys"`
+	KeyBytes   int64  `json:"key_bytes"`
+	ValueBytes int64  `json:"value_bytes"`
+	MinValue   int64  `json:"min_value"`
+	MaxValue   int64  `json:"max_value"`
+	AvgValue   int64  `json:"avg_value"`
+	Expired    int    `json:"expired"` // expired by TTL but not yet compacted away, not in other counts
+}
+
+type StatsReport struct {
+	Time   time.Time     `json:"time"`
+	Groups []PrefixStats `json:"groups"`
+	Total  PrefixStats   `json:"total"`
+}
+
+// group prefix of key under opt
+func (opt StatsOptions) group(key []byte) []byte {
+	if bytes.HasPrefix(key, metaPrefix) && !bytes.HasPrefix(opt.Prefix, metaPrefix) {
+		return metaPrefix
+	}
+	if len(opt.Separator) == 0 {
+		return key[:min(opt.Depth, len(key))]
+	}
+	end := 0
+	for range opt.Depth {
+		i := bytes.Index(key[end:], opt.Separator)
+		if i < 0 {
+			break
+		}
+		end += i + len(opt.Separator)
+	}
+	return key[:end]
+}
+
+func printable(prefix []byte) string {
+	if bytes.Equal(prefix, metaPrefix) {
+		return "<meta>"
+	}
+	if len(prefix) == 0 {
+		return "<all>"
+	}
+	q := strconv.Quote(string(prefix))
+	return q[1 : len(q)-1]
+}
+
+func (s *PrefixStats) add(item *badger.Item) {
+	if item.ExpiresAt() > 0 && item.IsDeletedOrExpired() {
+		s.Expired++
+		return
+	}
+	size := item.ValueSize()
+	if s.Keys == 0 || size < s.MinValue {
+		s.MinValue = size
+	}
+	s.MaxValue = max(s.MaxValue, size)
+	s.Keys++
+	s.KeyBytes += int64(len(item.Key()))
+	s.ValueBytes += size
+	s.AvgValue = s.ValueBytes / int64(s.Keys)
+}
+
+func (s *PrefixStats) merge(o PrefixStats) {
+	if o.Keys > 0 && (s.Keys == 0 || o.MinValue < s.MinValue) {
+		s.MinValue = o.MinValue
+	}
+	s.MaxValue = max(s.MaxValue, o.MaxValue)
+	s.Keys += o.Keys
+	s.KeyBytes += o.KeyBytes
+	s.ValueBytes += o.ValueBytes
+	s.Expired += o.Expired
+	if s.Keys > 0 {
+		s.AvgValue = s.ValueBytes / int64(s.Keys)
+	}
+}
+
+// walk latest version of each key in db, grouped by opt. deleted keys are not counted.
+func StorageStats(db *badger.DB, opt StatsOptions) (*StatsReport, error) {
+	report := &StatsReport{Time: time.Now()}
+	groups := map[string]*PrefixStats{}
+
+	err := db.View(func(txn *badger.Txn) error {
+		// expired keys are only visible with all versions
+		itOpt := keyOnlyOptions(opt.Prefix)
+		itOpt.AllVersions = true
+		it := txn.NewIterator(itOpt)
+		defer it.Close()
+
+		var last []byte
+		for seekPrefix(it, opt.Prefix, false); it.ValidForPrefix(opt.Prefix); it.Next() {
+			item := it.Item()
+			key := item.Key()
+			if bytes.Equal(key, last) {
+				continue // older version
+			}
+			last = append(last[:0], key...)
+
+			if item.IsDeletedOrExpired() && item.ExpiresAt() == 0 {
+				continue // deleted
+			}
+			g := opt.group(key)
+			if bytes.Equal(g, metaPrefix) && !opt.Meta {
+				continue
+			}
+			s, ok := groups[string(g)]
+			if !ok {
+				s = &PrefixStats{Prefix: bytes.Clone(g), Name: printable(g)}
+				groups[string(g)] = s
+			}
+			s.add(item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.Groups = make([]PrefixStats, 0, len(groups))
+	for _, s := range groups {
+		report.Groups = append(report.Groups, *s)
+	}
+	slices.SortFunc(report.Groups, func(a, b PrefixStats) int {
+		return bytes.Compare(a.Prefix, b.Prefix)
+	})
+	report.Total = PrefixStats{Name: "TOTAL"}
+	for _, s := range report.Groups {
+		report.Total.merge(s)
+	}
+	return report, nil
+}
+
+// write report as aligned text table, total in the last row
+func (r *StatsReport) Table(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "PREFIX\tKEYS\tKEY BYTES\tVALUE BYTES\tMIN\tAVG\tMAX\tEXPIRED\t")
+	for _, s := range append(slices.Clone(r.Groups), r.Total) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t\n",
+			s.Name, s.Keys, s.KeyBytes, s.ValueBytes, s.MinValue, s.AvgValue, s.MaxValue, s.Expired)
+	}
+	return tw.Flush()
+}
+
+// write report as indented JSON
+func (r *StatsReport) JSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(r)
+}
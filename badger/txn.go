@@ -0,0 +1,134 @@
+package badgerhelper
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// default retry budget of WithTxn when commit fails with badger.ErrConflict
+var TxnRetries = 3
+
+// one read-write transaction shared by several Tx* helper calls
+type Tx struct {
+	Txn *badger.Txn
+}
+
+// run fn in one read-write transaction of db, commit once fn returns nil.
+// fn may run more than once on conflict, so it must not keep side effects outside of tx.
+func WithTxn(db *badger.DB, fn func(tx *Tx) error) error {
+	return WithTxnRetry(db, TxnRetries, fn)
+}
+
+// same as WithTxn, with a specific retry budget on badger.ErrConflict
+func WithTxnRetry(db *badger.DB, retries int, fn func(tx *Tx) error) error {
+	for i := 0; ; i++ {
+		err := db.Update(func(txn *badger.Txn) error {
+			return fn(&Tx{Txn: txn})
+		})
+		if errors.Is(err, badger.ErrConflict) && i < retries {
+			continue
+		}
+		return err
+	}
+}
+
+// -------------------------------------------------------------------- //
+
+func TxGetOneObject[V any, T PtrDbAccessible[V]](tx *Tx, key []byte) (T, error) {
+	return getOneObject[V, T](tx.Txn, key)
+}
+
+func TxGetObjects[V any, T PtrDbAccessible[V]](tx *Tx, prefix []byte, filter func(T) bool) ([]T, error) {
+	return getObjects[V, T](tx.Txn, prefix, filter, false)
+}
+
+func TxGetObjectCount[V any, T PtrDbAccessible[V]](tx *Tx, prefix []byte, filter func(T) bool) (int, error) {
+	return getObjectCount[V, T](tx.Txn, prefix, filter)
+}
+
+func TxUpsertOneObject[V any, T PtrDbAccessible[V]](tx *Tx, object T, opts ...EntryOption) error {
+	return upsertOneObject[V, T](tx.Txn, object, opts...)
+}
+
+func TxUpsertObjects[V any, T PtrDbAccessible[V]](tx *Tx, objects ...T) error {
+	for _, object := range objects {
+		if err := upsertOneObject[V, T](tx.Txn, object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TxDeleteOneObject[V any, T PtrDbAccessible[V]](tx *Tx, key []byte) (int, error) {
+	return deleteOneObject[V, T](tx.Txn, key, nil)
+}
+
+func TxDeleteObjects[V any, T PtrDbAccessible[V]](tx *Tx, prefix []byte) (int, error) {
+	return deleteObjects[V, T](tx.Txn, prefix, nil)
+}
+
+func TxDeleteOneObjectReturning[V any, T PtrDbAccessible[V]](tx *Tx, key []byte) (int, T, error) {
+	taken := []T{}
+	n, err := deleteOneObject[V, T](tx.Txn, key, &taken)
+	if err != nil || len(taken) == 0 {
+		return 0, nil, err
+	}
+	return n, taken[0], nil
+}
+
+func TxDeleteObjectsReturning[V any, T PtrDbAccessible[V]](tx *Tx, prefix []byte) (int, []T, error) {
+	taken := []T{}
+	n, err := deleteObjects[V, T](tx.Txn, prefix, &taken)
+	if err != nil {
+		return n, nil, err
+	}
+	return n, taken, nil
+}
+
+// -------------------------------------------------------------------- //
+
+// load object at key, apply fn and write it back in one transaction, retried on badger.ErrConflict.
+// if object is absent, fn gets a fresh object when create is true (fn must fill its key),
+// otherwise fn is not called and nil is returned. TTL & user meta of stored object are kept.
+func ModifyObject[V any, T PtrDbAccessible[V]](key []byte, fn func(T) (T, error), create bool) (rt T, err error) {
+	err = WithTxn(T(new(V)).BadgerDB(), func(tx *Tx) error {
+		rt = nil
+		one, err := getOneObject[V, T](tx.Txn, key)
+		if err != nil {
+			return err
+		}
+		opts := []EntryOption{}
+		if one == nil {
+			if !create {
+				return nil
+			}
+			one = T(new(V))
+		} else {
+			item, err := tx.Txn.Get(key)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, withExpiresAt(item.ExpiresAt()), WithUserMeta(item.UserMeta()))
+		}
+		if one, err = fn(one); err != nil {
+			return err
+		}
+		if one == nil {
+			return errors.New("modified object CANNOT be nil")
+		}
+		if !bytes.Equal(key, one.Key()) {
+			return errors.New("modified object.Key MUST be identical to input key")
+		}
+		if err = upsertOneObject[V, T](tx.Txn, one, opts...); err != nil {
+			return err
+		}
+		rt = one
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rt, nil
+}
@@ -0,0 +1,124 @@
+package badgerhelper
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// object with its badger item version, which grows on every write of the key
+type Versioned[T any] struct {
+	Object  T
+	Version uint64
+}
+
+// match any *VersionConflictError by errors.Is
+var ErrVersionConflict = errors.New("object version conflict")
+
+type VersionConflictError struct {
+	Key      []byte
+	Expected uint64 // 0 means object was expected to be absent
+	Actual   uint64 // 0 means object is absent
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%v on key '%s': expected %d, actual %d", ErrVersionConflict, e.Key, e.Expected, e.Actual)
+}
+
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// current version of key in txn, 0 if absent
+func keyVersion(txn *badger.Txn, key []byte) (uint64, error) {
+	item, err := txn.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return item.Version(), nil
+}
+
+// one object with fixed key and its version, version is 0 if not found
+func GetOneObjectVersion[V any, T PtrDbAccessible[V]](key []byte) (rt T, ver uint64, err error) {
+	err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		one := T(new(V))
+		if err := item.Value(func(val []byte) error {
+			_, err := one.Unmarshal(key, val)
+			return err
+		}); err != nil {
+			return err
+		}
+		rt, ver = one, item.Version()
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return rt, ver, nil
+}
+
+// all objects with their versions if prefix is nil or empty
+func GetObjectsVersion[V any, T PtrDbAccessible[V]](prefix []byte, filter func(T) bool) ([]Versioned[T], error) {
+	var (
+		rt  = []Versioned[T]{}
+		err = T(new(V)).BadgerDB().View(func(txn *badger.Txn) error {
+			it := txn.NewIterator(scanOptions(prefix, false))
+			defer it.Close()
+
+			itemProc := func(item *badger.Item) error {
+				return item.Value(func(val []byte) error {
+					one := T(new(V))
+					if _, err := one.Unmarshal(item.Key(), val); err != nil {
+						return err
+					}
+					if filter == nil || filter(one) {
+						rt = append(rt, Versioned[T]{Object: one, Version: item.Version()})
+					}
+					return nil
+				})
+			}
+			for seekPrefix(it, prefix, false); it.ValidForPrefix(prefix); it.Next() {
+				if hidden(prefix, it.Item().Key()) {
+					continue
+				}
+				if err := itemProc(it.Item()); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	)
+	return rt, err
+}
+
+// store object only if its stored version is still expected, 0 expected means object must be absent.
+// return *VersionConflictError (matching ErrVersionConflict) if version changed.
+func UpsertIfVersion[V any, T PtrDbAccessible[V]](object T, expected uint64) error {
+	return WithTxn(object.BadgerDB(), func(tx *Tx) error {
+		key := object.Key()
+		actual, err := keyVersion(tx.Txn, key)
+		if err != nil {
+			return err
+		}
+		if actual != expected {
+			return &VersionConflictError{Key: key, Expected: expected, Actual: actual}
+		}
+		return upsertOneObject[V, T](tx.Txn, object)
+	})
+}
+
+// store object only if its key does not exist yet, otherwise return *VersionConflictError
+func InsertIfAbsent[V any, T PtrDbAccessible[V]](object T) error {
+	return UpsertIfVersion[V, T](object, 0)
+}
@@ -0,0 +1,66 @@
+package badgerhelper
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/dgraph-io/badger/v4/pb"
+)
+
+type EventType int
+
+const (
+	EventPut EventType = iota
+	EventDelete
+)
+
+func (et EventType) String() string {
+	switch et {
+	case EventPut:
+		return "put"
+	case EventDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// one committed write under watched prefix, Object is nil for delete
+type Event[T any] struct {
+	Type    EventType
+	Key     []byte
+	Object  T
+	Version uint64
+}
+
+// watch committed writes under prefix (all keys if prefix is nil or empty), fn gets typed events in commit order.
+// badger publishes deletes as entries without value, so an empty value is reported as EventDelete.
+// return nil when ctx is done or db is closed, or the first error from Unmarshal or fn.
+func Watch[V any, T PtrDbAccessible[V]](ctx context.Context, prefix []byte, fn func(Event[T]) error) error {
+	err := T(new(V)).BadgerDB().Subscribe(ctx, func(kvs *badger.KVList) error {
+		for _, kv := range kvs.GetKv() {
+			if hidden(prefix, kv.Key) {
+				continue
+			}
+			ev := Event[T]{Type: EventDelete, Key: kv.Key, Version: kv.Version}
+			if len(kv.Value) > 0 {
+				one := T(new(V))
+				if _, err := one.Unmarshal(kv.Key, kv.Value); err != nil {
+					return err
+				}
+				ev.Type, ev.Object = EventPut, one
+			}
+			if err := fn(ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, []pb.Match{{Prefix: prefix}})
+
+	// badger returns ctx.Err() once ctx is done, that is a normal end of watching
+	if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		return nil
+	}
+	return err
+}